@@ -18,6 +18,9 @@ export const API_ENDPOINTS = {
     INSERT: '/insertPick',
     GET_PICKS_FOR_EVENT: '/api/v1/getPicksForEvent',
     GET_PICKS_FOR_USER_AND_EVENT: '/api/v1/getPicksForUserAndEvent',
-    GET_PICKS_FOR_MATCHUP: '/api/v1/getPicksForMatchup'
+    GET_PICKS_FOR_MATCHUP: '/api/v1/getPicksForMatchup',
+    GET_CONSENSUS_FOR_MATCHUP: '/api/v1/getConsensusForMatchup',
+    GET_CONSENSUS_FOR_EVENT: '/api/v1/getConsensusForEvent',
+    STREAM_EVENT: '/api/v1/streamEvent'
   }
 } as const;
\ No newline at end of file
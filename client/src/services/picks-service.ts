@@ -1,6 +1,6 @@
 import { apiClient } from '@/lib/api-client';
 import { API_ENDPOINTS, PICKS_BASE_URL } from '@/config/api';
-import { ApiResponse, Pick } from '@/types/api';
+import { ApiResponse, EventConsensus, Pick, PickPage } from '@/types/api';
 
 export const PicksService = {
     async submitPick(userId: number, matchupId: string, eventId: string, fighterId: string): Promise<void> {
@@ -10,8 +10,17 @@ export const PicksService = {
         formData.append('eventId', eventId);
         formData.append('selectionId', fighterId);
 
+        // picks-service derives the user from the auth token, not the userId field
+        const headers: HeadersInit = {};
+        const storedToken = localStorage.getItem('auth_token');
+        if (storedToken) {
+            headers['Authorization'] = `Bearer ${storedToken}`;
+        }
+
         const response = await fetch(`${PICKS_BASE_URL}/insertPick`, {
             method: 'POST',
+            headers,
+            credentials: 'include',
             body: formData
         });
 
@@ -23,14 +32,49 @@ export const PicksService = {
     },
 
     async getPicksForUserAndEvent(userId: number, eventId: string): Promise<Pick[]> {
+        // the endpoint is paged; follow next_cursor until every pick of the event is loaded
+        const picks: Pick[] = [];
+        let cursor: string | null = null;
+
+        // only the user themselves (or an admin) may read their picks
+        const headers: HeadersInit = {};
+        const storedToken = localStorage.getItem('auth_token');
+        if (storedToken) {
+            headers['Authorization'] = `Bearer ${storedToken}`;
+        }
+
+        do {
+            const params = new URLSearchParams({ userId: userId.toString(), eventId });
+            if (cursor) {
+                params.set('cursor', cursor);
+            }
+
+            const response = await fetch(
+                `${PICKS_BASE_URL}${API_ENDPOINTS.PICKS.GET_PICKS_FOR_USER_AND_EVENT}?${params.toString()}`,
+                { headers, credentials: 'include' }
+            );
+
+            if (!response.ok) {
+                throw new Error('Failed to get picks');
+            }
+
+            const page: PickPage = await response.json();
+            picks.push(...page.data);
+            cursor = page.next_cursor;
+        } while (cursor);
+
+        return picks;
+    },
+
+    async getConsensusForEvent(eventId: string): Promise<EventConsensus> {
         const response = await fetch(
-            `${PICKS_BASE_URL}/api/v1/getPicksForUserAndEvent?userId=${userId}&eventId=${eventId}`
+            `${PICKS_BASE_URL}${API_ENDPOINTS.PICKS.GET_CONSENSUS_FOR_EVENT}?eventId=${eventId}`
         );
-        
+
         if (!response.ok) {
-            throw new Error('Failed to get picks');
+            throw new Error('Failed to get consensus');
         }
-        
+
         return response.json();
     }
 }; 
\ No newline at end of file
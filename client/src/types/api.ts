@@ -160,10 +160,36 @@ export interface Pick {
   event_id: string;
   selection_fighter_id: string;
   pick_result: string;
+  probability?: number;
   created_at: string;
   updated_at: string;
 }
 
+export interface PickPage {
+  data: Pick[];
+  next_cursor: string | null;
+  total: number;
+}
+
+export interface FighterConsensus {
+  fighter_id: string;
+  picks: number;
+  percentage: number;
+}
+
+export interface MatchupConsensus {
+  matchup_id: string;
+  event_id: string;
+  total_picks: number;
+  fighters: FighterConsensus[];
+}
+
+export interface EventConsensus {
+  event_id: string;
+  total_picks: number;
+  matchups: MatchupConsensus[];
+}
+
 export interface MatchupPrediction {
   matchup_id: string;
   fighter1_win_probability: number;
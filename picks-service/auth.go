@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"shared/auth"
+)
+
+var jwtKey []byte
+
+type contextKey string
+
+const claimsContextKey contextKey = "claims"
+
+// authenticate validates the same token the server issues on login (cookie or Bearer) and
+// stores its claims on the request context for the wrapped handler
+func authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := auth.ClaimsFromRequest(r, jwtKey)
+		if err != nil {
+			log.Printf("Token validation failed: %v", err)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if _, err := strconv.Atoi(claims.UserId); err != nil {
+			log.Printf("Token has invalid userId claim: %q", claims.UserId)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey, claims)))
+	}
+}
+
+// claimsFromContext returns the claims stored by authenticate
+func claimsFromContext(r *http.Request) (*auth.Claims, bool) {
+	claims, ok := r.Context().Value(claimsContextKey).(*auth.Claims)
+	return claims, ok
+}
+
+// authenticatedUserId returns the user id of the request's token. only valid behind authenticate
+func authenticatedUserId(r *http.Request) (int, bool) {
+	claims, ok := claimsFromContext(r)
+	if !ok {
+		return 0, false
+	}
+
+	userId, err := strconv.Atoi(claims.UserId)
+	if err != nil {
+		return 0, false
+	}
+	return userId, true
+}
+
+// optionalUserId returns the user id of a valid token on the request, or 0 for anonymous requests.
+// used by public endpoints that add extra detail for signed in users
+func optionalUserId(r *http.Request) int {
+	claims, err := auth.ClaimsFromRequest(r, jwtKey)
+	if err != nil {
+		return 0
+	}
+
+	userId, err := strconv.Atoi(claims.UserId)
+	if err != nil {
+		return 0
+	}
+	return userId
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"picks-service/db"
+)
+
+// how often matchups that just locked are checked for closing odds. Well under db.ClosingOddsGrace so a
+// missed tick still captures the line
+const closingOddsTick = time.Minute
+
+// runClosingOddsCapture captures the closing odds of matchups as they lock in store until the process exits
+func runClosingOddsCapture(store *db.PostgresPickStore) {
+	ticker := time.NewTicker(closingOddsTick)
+	defer ticker.Stop()
+
+	for {
+		captured, err := store.CaptureClosingOdds(time.Now())
+		if err != nil {
+			log.Printf("Error capturing closing odds: %v", err)
+		} else if captured > 0 {
+			log.Printf("Captured closing odds for %d matchups", captured)
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"picks-service/db"
+)
+
+// replaces the user's whole confidence assignment for an event:
+// {"eventId": "...", "picks": [{"matchupId": "...", "selectionId": "...", "confidence": 1}, ...]}
+func submitConfidencePicksHandler(store db.PickStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userId, _ := authenticatedUserId(r)
+
+		var req struct {
+			EventID string              `json:"eventId"`
+			Picks   []db.ConfidencePick `json:"picks"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if req.EventID == "" {
+			http.Error(w, "Missing field: eventId", http.StatusBadRequest)
+			return
+		}
+
+		err := store.UpsertConfidencePicks(userId, req.EventID, req.Picks, pickMetaFromRequest(r, db.PickSourceConfidence))
+		if err != nil {
+			log.Printf("Error saving confidence picks for user %d: %v", userId, err)
+
+			switch {
+			case errors.Is(err, db.ErrPickLocked):
+				http.Error(w, err.Error(), http.StatusLocked)
+			case errors.Is(err, db.ErrInvalidConfidence), errors.Is(err, db.ErrInvalidPick):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				http.Error(w, "Error saving confidence picks", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		writeJSON(w, map[string]string{"message": "Successfully saved confidence picks!"})
+	}
+}
+
+func getConfidenceStandingsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	eventId := r.URL.Query().Get("eventId")
+	if eventId == "" {
+		http.Error(w, "Missing query parameter: eventId", http.StatusBadRequest)
+		return
+	}
+
+	standings, err := db.GetConfidenceStandings(eventId)
+	if err != nil {
+		log.Printf("Error retrieving confidence standings: %v", err)
+		http.Error(w, "Error retrieving confidence standings", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, standings)
+}
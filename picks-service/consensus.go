@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"picks-service/db"
+)
+
+// pick counts and percentages per fighter for one matchup
+func getConsensusForMatchupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	matchupId := r.URL.Query().Get("matchupId")
+	if matchupId == "" {
+		http.Error(w, "Missing query parameter: matchupId", http.StatusBadRequest)
+		return
+	}
+
+	consensus, err := db.GetMatchupConsensus(matchupId)
+	if err != nil {
+		log.Printf("Error retrieving consensus for matchup %s: %v", matchupId, err)
+		http.Error(w, "Error retrieving consensus", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, consensus)
+}
+
+// pick counts and percentages per fighter for every matchup of an event
+func getConsensusForEventHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	eventId := r.URL.Query().Get("eventId")
+	if eventId == "" {
+		http.Error(w, "Missing query parameter: eventId", http.StatusBadRequest)
+		return
+	}
+
+	consensus, err := db.GetEventConsensus(eventId)
+	if err != nil {
+		log.Printf("Error retrieving consensus for event %s: %v", eventId, err)
+		http.Error(w, "Error retrieving consensus", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, consensus)
+}
@@ -0,0 +1,385 @@
+// usersDBAchievementsUtils awards badges to users after grading. Every badge is an AchievementRule in
+// achievementRules; adding a badge only takes adding a rule there
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// AchievementRule decides from a user's graded picks whether they earned a badge. Earned returns the
+// event the badge was earned at, if it's tied to one
+type AchievementRule struct {
+	Code        string
+	Name        string
+	Description string
+	Earned      func(record PickerRecord) (eventID string, ok bool)
+}
+
+// GradedPick is a correct or incorrect pick with what the rules need to know about its fight
+type GradedPick struct {
+	MatchupID  string
+	EventID    string
+	CardType   string
+	Correct    bool
+	OddsAtPick *int
+	GradedAt   time.Time
+}
+
+// MainCard is how many of an event's main card fights had a winner and how many are still to be graded
+type MainCard struct {
+	Decided   int
+	Ungraded  int
+	UserRight int
+}
+
+// achievementRecentPicks is how many of the latest graded picks rules get to see. It has to cover the
+// longest streak any rule looks for
+const achievementRecentPicks = 10
+
+// PickerRecord is everything rules get to look at. Rules run after every graded matchup, so instead of the
+// user's whole history they see what grading one more fight can change: their latest graded picks, totals,
+// their best underdog win and the main cards of the events in their latest picks
+type PickerRecord struct {
+	UserID       int
+	CorrectPicks int
+	// Recent holds the latest achievementRecentPicks graded picks, oldest first
+	Recent []GradedPick
+	// BestUnderdog is the correct pick made at the longest odds, if any had odds
+	BestUnderdog *GradedPick
+	MainCards    map[string]MainCard
+}
+
+type UserAchievement struct {
+	Code        string     `json:"code"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Earned      bool       `json:"earned"`
+	EventID     *string    `json:"event_id,omitempty"`
+	AwardedAt   *time.Time `json:"awarded_at,omitempty"`
+}
+
+var achievementRules = []AchievementRule{
+	{
+		Code:        "first_correct",
+		Name:        "On the Board",
+		Description: "Get your first pick right",
+		Earned:      correctPicks(1),
+	},
+	{
+		Code:        "streak_5",
+		Name:        "Hot Streak",
+		Description: "Get 5 picks in a row right",
+		Earned:      correctInARow(5),
+	},
+	{
+		Code:        "streak_10",
+		Name:        "Unstoppable",
+		Description: "Get 10 picks in a row right",
+		Earned:      correctInARow(10),
+	},
+	{
+		Code:        "underdog_300",
+		Name:        "Live Dog",
+		Description: "Correctly pick a +300 or longer underdog",
+		Earned: func(record PickerRecord) (string, bool) {
+			if p := record.BestUnderdog; p != nil && *p.OddsAtPick >= 300 {
+				return p.EventID, true
+			}
+			return "", false
+		},
+	},
+	{
+		Code:        "perfect_main_card",
+		Name:        "Perfect Main Card",
+		Description: "Get every decided fight on a main card right",
+		Earned: func(record PickerRecord) (string, bool) {
+			for eventId, card := range record.MainCards {
+				if card.Ungraded == 0 && card.Decided > 0 && card.UserRight == card.Decided {
+					return eventId, true
+				}
+			}
+			return "", false
+		},
+	},
+	{
+		Code:        "correct_100",
+		Name:        "Centurion",
+		Description: "Get 100 picks right",
+		Earned:      correctPicks(100),
+	},
+}
+
+// correctPicks builds a rule earned by n correct picks in total. The badge is tied to the event of the
+// latest correct pick, which is the one that got there when rules run after every grading
+func correctPicks(n int) func(PickerRecord) (string, bool) {
+	return func(record PickerRecord) (string, bool) {
+		if record.CorrectPicks < n {
+			return "", false
+		}
+		for i := len(record.Recent) - 1; i >= 0; i-- {
+			if record.Recent[i].Correct {
+				return record.Recent[i].EventID, true
+			}
+		}
+		return "", true
+	}
+}
+
+// correctInARow builds a rule earned by n consecutive correct picks. n can't be more than achievementRecentPicks
+func correctInARow(n int) func(PickerRecord) (string, bool) {
+	return func(record PickerRecord) (string, bool) {
+		run := 0
+		for _, p := range record.Recent {
+			if !p.Correct {
+				run = 0
+				continue
+			}
+			run++
+			if run == n {
+				return p.EventID, true
+			}
+		}
+		return "", false
+	}
+}
+
+// achievementQueue holds graded matchups waiting for their pickers' achievements to be evaluated. A single
+// worker drains it so grading never waits on the rules and a user is never evaluated twice at once
+var (
+	achievementQueue     = make(chan string, 1024)
+	achievementQueueOnce sync.Once
+)
+
+// queueAchievements schedules awardAchievements for a matchup that was just graded and committed
+func queueAchievements(matchup_id string) {
+	achievementQueueOnce.Do(func() {
+		go func() {
+			for id := range achievementQueue {
+				awardAchievements(id)
+			}
+		}()
+	})
+
+	select {
+	case achievementQueue <- matchup_id:
+	default:
+		log.Printf("Achievement queue is full, skipping achievements for matchup %s", matchup_id)
+	}
+}
+
+// awardAchievements evaluates every rule for the users who picked a matchup, right after it was graded.
+// Badges are kept once awarded, even if a later regrade would no longer earn them
+func awardAchievements(matchup_id string) {
+	rows, err := usersDb.Query("SELECT DISTINCT user_id FROM public.picks WHERE matchup_id = $1;", matchup_id)
+	if err != nil {
+		log.Printf("Error finding users to evaluate achievements for matchup %s: %v", matchup_id, err)
+		return
+	}
+
+	var userIds []int
+	for rows.Next() {
+		var userId int
+		if err := rows.Scan(&userId); err != nil {
+			rows.Close()
+			log.Printf("Error scanning user for achievements: %v", err)
+			return
+		}
+		userIds = append(userIds, userId)
+	}
+	rows.Close()
+
+	for _, userId := range userIds {
+		awarded, err := EvaluateAchievements(userId)
+		if err != nil {
+			log.Printf("Error evaluating achievements for user %d: %v", userId, err)
+			continue
+		}
+		for _, code := range awarded {
+			log.Printf("Awarded achievement %s to user %d", code, userId)
+		}
+	}
+}
+
+// EvaluateAchievements runs every rule the user hasn't earned yet and stores the new ones.
+// It returns the codes awarded by this call
+func EvaluateAchievements(userID int) ([]string, error) {
+	earned := make(map[string]bool)
+	rows, err := usersDb.Query("SELECT achievement_code FROM public.user_achievements WHERE user_id = $1;", userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying achievements for user %d: %w", userID, err)
+	}
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning achievement row: %w", err)
+		}
+		earned[code] = true
+	}
+	rows.Close()
+
+	if len(earned) == len(achievementRules) {
+		return nil, nil
+	}
+
+	record, err := loadPickerRecord(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var awarded []string
+	sqlAward := `INSERT INTO public.user_achievements (user_id, achievement_code, event_id) VALUES ($1, $2, NULLIF($3, ''))
+		ON CONFLICT (user_id, achievement_code) DO NOTHING;`
+	for _, rule := range achievementRules {
+		if earned[rule.Code] {
+			continue
+		}
+		eventId, ok := rule.Earned(record)
+		if !ok {
+			continue
+		}
+		res, err := usersDb.Exec(sqlAward, userID, rule.Code, eventId)
+		if err != nil {
+			return awarded, fmt.Errorf("unable to award %s to user %d: %w", rule.Code, userID, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			awarded = append(awarded, rule.Code)
+		}
+	}
+	return awarded, nil
+}
+
+// loadPickerRecord reads what the rules look at: the user's latest graded picks, their correct pick count,
+// their best underdog win and the state of the main cards among their latest picks
+func loadPickerRecord(userID int) (PickerRecord, error) {
+	record := PickerRecord{UserID: userID, MainCards: make(map[string]MainCard)}
+
+	sqlCount := "SELECT COUNT(*) FROM public.picks WHERE user_id = $1 AND pick_result = $2;"
+	if err := usersDb.QueryRow(sqlCount, userID, PickResultCorrect).Scan(&record.CorrectPicks); err != nil {
+		return record, fmt.Errorf("error counting correct picks for user %d: %w", userID, err)
+	}
+
+	sqlRecent := `SELECT p.matchup_id, p.event_id, COALESCE(ms.card_type, ''), p.pick_result = $2, p.odds_at_pick, mr.graded_at
+		FROM public.picks p
+		JOIN public.matchup_results mr ON mr.matchup_id = p.matchup_id
+		LEFT JOIN public.matchup_schedule ms ON ms.matchup_id = p.matchup_id
+		WHERE p.user_id = $1 AND p.pick_result IN ($2, $3)
+		ORDER BY mr.graded_at DESC, p.pick_id DESC
+		LIMIT $4;`
+	recent, err := queryGradedPicks(sqlRecent, userID, PickResultCorrect, PickResultIncorrect, achievementRecentPicks)
+	if err != nil {
+		return record, err
+	}
+	for i, j := 0, len(recent)-1; i < j; i, j = i+1, j-1 {
+		recent[i], recent[j] = recent[j], recent[i]
+	}
+	record.Recent = recent
+
+	sqlUnderdog := `SELECT p.matchup_id, p.event_id, COALESCE(ms.card_type, ''), TRUE, p.odds_at_pick, mr.graded_at
+		FROM public.picks p
+		JOIN public.matchup_results mr ON mr.matchup_id = p.matchup_id
+		LEFT JOIN public.matchup_schedule ms ON ms.matchup_id = p.matchup_id
+		WHERE p.user_id = $1 AND p.pick_result = $2 AND p.odds_at_pick IS NOT NULL
+		ORDER BY p.odds_at_pick DESC, mr.graded_at
+		LIMIT 1;`
+	underdog, err := queryGradedPicks(sqlUnderdog, userID, PickResultCorrect)
+	if err != nil {
+		return record, err
+	}
+	if len(underdog) > 0 {
+		record.BestUnderdog = &underdog[0]
+	}
+
+	sqlCard := `SELECT COUNT(*) FILTER (WHERE mr.outcome = $3),
+			COUNT(*) FILTER (WHERE mr.matchup_id IS NULL),
+			COUNT(*) FILTER (WHERE p.pick_result = $4)
+		FROM public.matchup_schedule ms
+		LEFT JOIN public.matchup_results mr ON mr.matchup_id = ms.matchup_id
+		LEFT JOIN public.picks p ON p.matchup_id = ms.matchup_id AND p.event_id = ms.event_id AND p.user_id = $1
+		WHERE ms.event_id = $2 AND ms.card_type = $5;`
+	for _, p := range record.Recent {
+		if _, seen := record.MainCards[p.EventID]; seen || p.CardType != CardTypeMainCard {
+			continue
+		}
+		var card MainCard
+		err := usersDb.QueryRow(sqlCard, userID, p.EventID, OutcomeWin, PickResultCorrect, CardTypeMainCard).Scan(&card.Decided, &card.Ungraded, &card.UserRight)
+		if err != nil {
+			return record, fmt.Errorf("error retrieving main card of event %s: %w", p.EventID, err)
+		}
+		record.MainCards[p.EventID] = card
+	}
+
+	return record, nil
+}
+
+// queryGradedPicks runs a query selecting matchup_id, event_id, card_type, correct, odds_at_pick and graded_at
+func queryGradedPicks(sqlStatement string, args ...interface{}) ([]GradedPick, error) {
+	rows, err := usersDb.Query(sqlStatement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying graded picks: %w", err)
+	}
+	defer rows.Close()
+
+	var picks []GradedPick
+	for rows.Next() {
+		var p GradedPick
+		var odds sql.NullInt64
+		if err := rows.Scan(&p.MatchupID, &p.EventID, &p.CardType, &p.Correct, &odds, &p.GradedAt); err != nil {
+			return nil, fmt.Errorf("error scanning graded pick row: %w", err)
+		}
+		if odds.Valid {
+			american := int(odds.Int64)
+			p.OddsAtPick = &american
+		}
+		picks = append(picks, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating graded pick rows: %w", err)
+	}
+	return picks, nil
+}
+
+// GetUserAchievements lists every badge, earned or not, so the profile page can show what's left to unlock
+func GetUserAchievements(userID int) ([]UserAchievement, error) {
+	type award struct {
+		eventID   sql.NullString
+		awardedAt time.Time
+	}
+	awards := make(map[string]award)
+
+	rows, err := usersDb.Query("SELECT achievement_code, event_id, awarded_at FROM public.user_achievements WHERE user_id = $1;", userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying achievements for user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var code string
+		var a award
+		if err := rows.Scan(&code, &a.eventID, &a.awardedAt); err != nil {
+			return nil, fmt.Errorf("error scanning achievement row: %w", err)
+		}
+		awards[code] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating achievement rows: %w", err)
+	}
+
+	achievements := make([]UserAchievement, 0, len(achievementRules))
+	for _, rule := range achievementRules {
+		a := UserAchievement{Code: rule.Code, Name: rule.Name, Description: rule.Description}
+		if award, ok := awards[rule.Code]; ok {
+			a.Earned = true
+			a.AwardedAt = &award.awardedAt
+			if award.eventID.Valid {
+				a.EventID = &award.eventID.String
+			}
+		}
+		achievements = append(achievements, a)
+	}
+	return achievements, nil
+}
@@ -0,0 +1,221 @@
+// usersDBAnalyticsUtils breaks a user's graded picks down by division, card section, line and finish method
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// bucket keys that aren't taken from the data itself. Unknown is used when a pick is missing the
+// metadata to place it, Other for fights that ended without a finish method (draws, DQs...)
+const (
+	AnalyticsUnknown  = "Unknown"
+	AnalyticsOther    = "Other"
+	AnalyticsFavorite = "favorite"
+	AnalyticsUnderdog = "underdog"
+	AnalyticsEven     = "even"
+)
+
+const (
+	StreakTypeWin  = "win"
+	StreakTypeLoss = "loss"
+)
+
+// the trend is bucketed by calendar month of grading
+const (
+	TrendPeriodMonth  = "month"
+	trendPeriodLayout = "2006-01"
+)
+
+type AccuracyBucket struct {
+	Key      string  `json:"key"`
+	Correct  int     `json:"correct"`
+	Total    int     `json:"total"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+type Streak struct {
+	Type   string `json:"type,omitempty"`
+	Length int    `json:"length"`
+}
+
+// TrendPoint is the accuracy within one period plus the running accuracy up to the end of it
+type TrendPoint struct {
+	Period             string  `json:"period"`
+	Correct            int     `json:"correct"`
+	Total              int     `json:"total"`
+	Accuracy           float64 `json:"accuracy"`
+	CumulativeAccuracy float64 `json:"cumulative_accuracy"`
+}
+
+type UserAnalytics struct {
+	UserID            int              `json:"user_id"`
+	Overall           AccuracyBucket   `json:"overall"`
+	ByWeightClass     []AccuracyBucket `json:"by_weight_class"`
+	ByCardSection     []AccuracyBucket `json:"by_card_section"`
+	ByLine            []AccuracyBucket `json:"by_line"`
+	ByMethod          []AccuracyBucket `json:"by_method"`
+	CurrentStreak     Streak           `json:"current_streak"`
+	LongestWinStreak  int              `json:"longest_win_streak"`
+	LongestLossStreak int              `json:"longest_loss_streak"`
+	TrendPeriod       string           `json:"trend_period"`
+	Trend             []TrendPoint     `json:"trend"`
+}
+
+// accuracyTally accumulates buckets in first-seen order
+type accuracyTally struct {
+	order   []string
+	buckets map[string]*AccuracyBucket
+}
+
+func newAccuracyTally() *accuracyTally {
+	return &accuracyTally{buckets: make(map[string]*AccuracyBucket)}
+}
+
+func (t *accuracyTally) add(key string, correct bool) {
+	b, ok := t.buckets[key]
+	if !ok {
+		b = &AccuracyBucket{Key: key}
+		t.buckets[key] = b
+		t.order = append(t.order, key)
+	}
+	b.Total++
+	if correct {
+		b.Correct++
+	}
+}
+
+// list returns the buckets with the most picks first
+func (t *accuracyTally) list() []AccuracyBucket {
+	list := make([]AccuracyBucket, 0, len(t.order))
+	for _, key := range t.order {
+		b := *t.buckets[key]
+		b.Accuracy = accuracy(b.Correct, b.Total)
+		list = append(list, b)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Total > list[j].Total })
+	return list
+}
+
+// lineBucket places a pick by the American odds it was made at
+func lineBucket(oddsAtPick sql.NullInt64) string {
+	switch {
+	case !oddsAtPick.Valid:
+		return AnalyticsUnknown
+	case oddsAtPick.Int64 == 100 || oddsAtPick.Int64 == -100:
+		return AnalyticsEven
+	case oddsAtPick.Int64 < 0:
+		return AnalyticsFavorite
+	}
+	return AnalyticsUnderdog
+}
+
+func orUnknown(s sql.NullString) string {
+	if !s.Valid || s.String == "" {
+		return AnalyticsUnknown
+	}
+	return s.String
+}
+
+// GetUserAnalytics computes a user's accuracy breakdowns, streaks and monthly trend from their correct and
+// incorrect picks, in the order the fights were graded. void, push and cancelled picks are left out
+func GetUserAnalytics(userID int) (UserAnalytics, error) {
+	analytics := UserAnalytics{UserID: userID, TrendPeriod: TrendPeriodMonth}
+
+	sqlStatement := `SELECT p.pick_result, p.odds_at_pick, ms.weight_class, ms.card_type, mr.method,
+			COALESCE(mr.graded_at, p.updated_at)
+		FROM public.picks p
+		LEFT JOIN public.matchup_schedule ms ON ms.matchup_id = p.matchup_id
+		LEFT JOIN public.matchup_results mr ON mr.matchup_id = p.matchup_id
+		WHERE p.user_id = $1 AND p.pick_result IN ($2, $3)
+		ORDER BY COALESCE(mr.graded_at, p.updated_at), p.pick_id;`
+	rows, err := usersDb.Query(sqlStatement, userID, PickResultCorrect, PickResultIncorrect)
+	if err != nil {
+		return analytics, fmt.Errorf("error querying graded picks for user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	weightClasses := newAccuracyTally()
+	cardSections := newAccuracyTally()
+	lines := newAccuracyTally()
+	methods := newAccuracyTally()
+	periods := newAccuracyTally()
+
+	var winRun, lossRun int
+	for rows.Next() {
+		var result string
+		var oddsAtPick sql.NullInt64
+		var weightClass, cardType, method sql.NullString
+		var gradedAt time.Time
+		if err := rows.Scan(&result, &oddsAtPick, &weightClass, &cardType, &method, &gradedAt); err != nil {
+			return analytics, fmt.Errorf("error scanning graded pick row: %w", err)
+		}
+
+		correct := result == PickResultCorrect
+		analytics.Overall.Total++
+		if correct {
+			analytics.Overall.Correct++
+		}
+
+		weightClasses.add(orUnknown(weightClass), correct)
+		cardSections.add(orUnknown(cardType), correct)
+		lines.add(lineBucket(oddsAtPick), correct)
+		if method.Valid && method.String != "" {
+			methods.add(method.String, correct)
+		} else {
+			methods.add(AnalyticsOther, correct)
+		}
+		periods.add(gradedAt.Format(trendPeriodLayout), correct)
+
+		if correct {
+			winRun++
+			lossRun = 0
+			if winRun > analytics.LongestWinStreak {
+				analytics.LongestWinStreak = winRun
+			}
+		} else {
+			lossRun++
+			winRun = 0
+			if lossRun > analytics.LongestLossStreak {
+				analytics.LongestLossStreak = lossRun
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return analytics, fmt.Errorf("error iterating graded pick rows: %w", err)
+	}
+
+	analytics.Overall.Key = "overall"
+	analytics.Overall.Accuracy = accuracy(analytics.Overall.Correct, analytics.Overall.Total)
+	analytics.ByWeightClass = weightClasses.list()
+	analytics.ByCardSection = cardSections.list()
+	analytics.ByLine = lines.list()
+	analytics.ByMethod = methods.list()
+
+	switch {
+	case winRun > 0:
+		analytics.CurrentStreak = Streak{Type: StreakTypeWin, Length: winRun}
+	case lossRun > 0:
+		analytics.CurrentStreak = Streak{Type: StreakTypeLoss, Length: lossRun}
+	}
+
+	//periods were added in graded order, so first-seen order is chronological
+	analytics.Trend = make([]TrendPoint, 0, len(periods.order))
+	var runningCorrect, runningTotal int
+	for _, key := range periods.order {
+		b := periods.buckets[key]
+		runningCorrect += b.Correct
+		runningTotal += b.Total
+		analytics.Trend = append(analytics.Trend, TrendPoint{
+			Period:             key,
+			Correct:            b.Correct,
+			Total:              b.Total,
+			Accuracy:           accuracy(b.Correct, b.Total),
+			CumulativeAccuracy: accuracy(runningCorrect, runningTotal),
+		})
+	}
+
+	return analytics, nil
+}
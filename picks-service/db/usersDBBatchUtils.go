@@ -0,0 +1,168 @@
+// usersDBBatchUtils submits a whole card of moneyline picks at once
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"picks-service/events"
+)
+
+var ErrInvalidBatch = errors.New("one or more picks in the batch are invalid")
+
+// largest card we expect, with room to spare
+const MaxBatchSize = 30
+
+// per-item statuses of a batch
+const (
+	BatchStatusInserted = "inserted"
+	BatchStatusUpdated  = "updated"
+	BatchStatusInvalid  = "invalid"
+	BatchStatusSkipped  = "skipped"
+)
+
+type BatchPick struct {
+	MatchupID          string `json:"matchupId"`
+	SelectionFighterID string `json:"selectionId"`
+}
+
+type BatchPickResult struct {
+	MatchupID string `json:"matchup_id"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// checkBatch validates every pick of a batch before anything is written. scheduled is the event's synced card
+// and checkLock the store's lock check. If any pick is invalid the invalid items carry their reason, the rest
+// are marked skipped and the returned error wraps ErrInvalidBatch
+func checkBatch(event_id string, picks []BatchPick, scheduled []string, source events.Source, checkLock func(matchup_id string) error) ([]BatchPickResult, error) {
+	if len(picks) == 0 || len(picks) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: a batch must contain between 1 and %d picks", ErrInvalidBatch, MaxBatchSize)
+	}
+
+	results := make([]BatchPickResult, len(picks))
+	valid, err := validateBatch(event_id, picks, results, scheduled, source, checkLock)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		for i := range results {
+			if results[i].Status == "" {
+				results[i].Status = BatchStatusSkipped
+			}
+		}
+		return results, ErrInvalidBatch
+	}
+	return results, nil
+}
+
+// validateBatch fills results with an error for each invalid pick and reports whether all were valid
+func validateBatch(event_id string, picks []BatchPick, results []BatchPickResult, scheduled []string, source events.Source, checkLock func(matchup_id string) error) (bool, error) {
+	//the real card is fetched once for the whole batch. an event that doesn't exist fails every pick
+	event, err := lookupEvent(source, event_id)
+	var cardErr error
+	if errors.Is(err, ErrInvalidPick) {
+		cardErr = err
+	} else if err != nil {
+		return false, err
+	}
+
+	//until the schedule is synced the event source's card decides what's on the event
+	cardMatchupIds := scheduled
+	if len(cardMatchupIds) == 0 && event != nil {
+		for _, m := range event.Matchups {
+			cardMatchupIds = append(cardMatchupIds, m.MatchupID)
+		}
+	}
+	if len(cardMatchupIds) == 0 && cardErr == nil {
+		cardErr = fmt.Errorf("%w: the card for event %s isn't known yet", ErrInvalidPick, event_id)
+	}
+	onCard := make(map[string]bool, len(cardMatchupIds))
+	for _, id := range cardMatchupIds {
+		onCard[id] = true
+	}
+
+	valid := true
+	seen := make(map[string]bool, len(picks))
+	for i, p := range picks {
+		results[i].MatchupID = p.MatchupID
+
+		var itemErr error
+		switch {
+		case p.MatchupID == "" || p.SelectionFighterID == "":
+			itemErr = errors.New("matchupId and selectionId are required")
+		case seen[p.MatchupID]:
+			itemErr = errors.New("matchup is picked more than once in this batch")
+		case cardErr != nil:
+			itemErr = cardErr
+		case !onCard[p.MatchupID]:
+			itemErr = fmt.Errorf("matchup is not part of event %s", event_id)
+		default:
+			itemErr = checkLock(p.MatchupID)
+			if itemErr == nil && event != nil {
+				itemErr = checkPickOnCard(*event, p.MatchupID, p.SelectionFighterID)
+			}
+		}
+		seen[p.MatchupID] = true
+
+		if itemErr != nil {
+			valid = false
+			results[i].Status = BatchStatusInvalid
+			results[i].Error = itemErr.Error()
+		}
+	}
+	return valid, nil
+}
+
+// UpsertPicksBatch validates every pick against the event's card and lock times, then writes all
+// of them in one transaction. If any pick is invalid nothing is written, the invalid items carry
+// their reason and the rest are marked skipped; the returned error then wraps ErrInvalidBatch
+func (s *PostgresPickStore) UpsertPicksBatch(user_id int, event_id string, picks []BatchPick, meta PickMeta) ([]BatchPickResult, error) {
+	scheduled, err := getEventMatchupIds(s.db, event_id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	results, err := checkBatch(event_id, picks, scheduled, s.events, func(matchup_id string) error {
+		return checkPickLock(s.db, s.lockMode, matchup_id, now)
+	})
+	if err != nil {
+		return results, err
+	}
+
+	//quoted before the transaction so a slow odds source doesn't hold locks
+	oddsAtPick := make([]*int, len(picks))
+	for i, p := range picks {
+		oddsAtPick[i] = lookupPickOdds(s.odds, p.MatchupID, p.SelectionFighterID)
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to start batch transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	userId := strconv.Itoa(user_id)
+	for i, p := range picks {
+		inserted, _, err := s.writePick(tx, userId, p.MatchupID, event_id, p.SelectionFighterID, oddsAtPick[i], meta)
+		if err != nil {
+			return nil, fmt.Errorf("matchup %s: %w", p.MatchupID, err)
+		}
+		if inserted {
+			results[i].Status = BatchStatusInserted
+		} else {
+			results[i].Status = BatchStatusUpdated
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit pick batch: %w", err)
+	}
+	for _, p := range picks {
+		invalidateConsensus(p.MatchupID, event_id)
+	}
+	return results, nil
+}
@@ -0,0 +1,238 @@
+// usersDBConfidenceUtils handles confidence pick mode: every fight on a card gets a unique confidence
+// value from 1 to N, and a user scores the sum of the values on their correct picks
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"picks-service/events"
+)
+
+var ErrInvalidConfidence = errors.New("invalid confidence assignment")
+
+type ConfidencePick struct {
+	MatchupID          string `json:"matchupId"`
+	SelectionFighterID string `json:"selectionId"`
+	Confidence         int    `json:"confidence"`
+}
+
+type ConfidenceStanding struct {
+	Rank          int    `json:"rank"`
+	UserID        int    `json:"user_id"`
+	Username      string `json:"username"`
+	Points        int    `json:"points"`
+	PossibleLeft  int    `json:"possible_left"`
+	CorrectPicks  int    `json:"correct_picks"`
+	GradedPicks   int    `json:"graded_picks"`
+	AssignedPicks int    `json:"assigned_picks"`
+}
+
+// getEventMatchupIds returns the synced matchups of an event (empty if the schedule wasn't synced)
+func getEventMatchupIds(ex dbExecutor, event_id string) ([]string, error) {
+	rows, err := ex.QueryContext(context.Background(), "SELECT matchup_id FROM public.matchup_schedule WHERE event_id = $1;", event_id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying matchups for event %s: %w", event_id, err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning matchup id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// eventCardMatchupIds returns the matchups of an event's card: the synced schedule, or the source's card
+// when the schedule hasn't been synced yet. It is empty when neither knows the event
+func eventCardMatchupIds(scheduled []string, source events.Source, event_id string) ([]string, error) {
+	if len(scheduled) > 0 {
+		return scheduled, nil
+	}
+
+	event, err := lookupEvent(source, event_id)
+	if errors.Is(err, ErrInvalidPick) {
+		return nil, nil
+	}
+	if err != nil || event == nil {
+		return nil, err
+	}
+	var ids []string
+	for _, m := range event.Matchups {
+		ids = append(ids, m.MatchupID)
+	}
+	return ids, nil
+}
+
+// ValidateConfidenceAssignment checks that every fight is picked once and the confidence values are
+// exactly 1..N. cardMatchupIds is the full card when known; when empty only the values are checked
+func ValidateConfidenceAssignment(picks []ConfidencePick, cardMatchupIds []string) error {
+	if len(picks) == 0 {
+		return fmt.Errorf("%w: no picks submitted", ErrInvalidConfidence)
+	}
+
+	n := len(picks)
+	seenMatchups := make(map[string]bool, n)
+	seenValues := make(map[int]bool, n)
+	for _, p := range picks {
+		if p.MatchupID == "" || p.SelectionFighterID == "" {
+			return fmt.Errorf("%w: every pick needs a matchupId and selectionId", ErrInvalidConfidence)
+		}
+		if seenMatchups[p.MatchupID] {
+			return fmt.Errorf("%w: matchup %s is picked more than once", ErrInvalidConfidence, p.MatchupID)
+		}
+		if p.Confidence < 1 || p.Confidence > n {
+			return fmt.Errorf("%w: confidence for matchup %s must be between 1 and %d", ErrInvalidConfidence, p.MatchupID, n)
+		}
+		if seenValues[p.Confidence] {
+			return fmt.Errorf("%w: confidence %d is used more than once", ErrInvalidConfidence, p.Confidence)
+		}
+		seenMatchups[p.MatchupID] = true
+		seenValues[p.Confidence] = true
+	}
+
+	if len(cardMatchupIds) == 0 {
+		return nil
+	}
+	if len(cardMatchupIds) != n {
+		return fmt.Errorf("%w: the card has %d fights but %d were ranked", ErrInvalidConfidence, len(cardMatchupIds), n)
+	}
+	for _, id := range cardMatchupIds {
+		if !seenMatchups[id] {
+			return fmt.Errorf("%w: matchup %s is missing", ErrInvalidConfidence, id)
+		}
+	}
+	return nil
+}
+
+// checkConfidencePicks validates a full confidence assignment before anything is written. scheduled is the
+// event's synced card and checkLock the store's lock check
+func checkConfidencePicks(event_id string, picks []ConfidencePick, scheduled []string, source events.Source, checkLock func(matchup_id string) error) error {
+	cardMatchupIds, err := eventCardMatchupIds(scheduled, source, event_id)
+	if err != nil {
+		return err
+	}
+	//without the card there is no telling a partial ranking from a full one
+	if len(cardMatchupIds) == 0 {
+		return fmt.Errorf("%w: the card for event %s isn't known yet", ErrInvalidConfidence, event_id)
+	}
+	if err := ValidateConfidenceAssignment(picks, cardMatchupIds); err != nil {
+		return err
+	}
+
+	event, err := lookupEvent(source, event_id)
+	if err != nil {
+		return err
+	}
+	if event != nil {
+		for _, p := range picks {
+			if err := checkPickOnCard(*event, p.MatchupID, p.SelectionFighterID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, p := range picks {
+		if err := checkLock(p.MatchupID); err != nil {
+			return fmt.Errorf("matchup %s: %w", p.MatchupID, err)
+		}
+	}
+	return nil
+}
+
+// UpsertConfidencePicks replaces a user's full confidence assignment for an event. The whole card is
+// validated first and written in one transaction, so either every pick is saved or none are
+func (s *PostgresPickStore) UpsertConfidencePicks(user_id int, event_id string, picks []ConfidencePick, meta PickMeta) error {
+	scheduled, err := getEventMatchupIds(s.db, event_id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = checkConfidencePicks(event_id, picks, scheduled, s.events, func(matchup_id string) error {
+		return checkPickLock(s.db, s.lockMode, matchup_id, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	//quoted before the transaction so a slow odds source doesn't hold locks
+	oddsAtPick := make(map[string]*int, len(picks))
+	for _, p := range picks {
+		oddsAtPick[p.MatchupID] = lookupPickOdds(s.odds, p.MatchupID, p.SelectionFighterID)
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("unable to start confidence transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	//clear the old values first so swapping two fights doesn't trip the unique index
+	sqlClear := "UPDATE public.picks SET confidence = NULL WHERE user_id = $1 AND event_id = $2 AND confidence IS NOT NULL;"
+	if _, err := tx.ExecContext(context.Background(), sqlClear, user_id, event_id); err != nil {
+		return fmt.Errorf("unable to clear confidence values: %w", err)
+	}
+
+	userId := strconv.Itoa(user_id)
+	sqlConfidence := "UPDATE public.picks SET confidence = $1 WHERE user_id = $2 AND matchup_id = $3 AND event_id = $4;"
+	for _, p := range picks {
+		if _, _, err := s.writePick(tx, userId, p.MatchupID, event_id, p.SelectionFighterID, oddsAtPick[p.MatchupID], meta); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(context.Background(), sqlConfidence, p.Confidence, user_id, p.MatchupID, event_id); err != nil {
+			return fmt.Errorf("unable to set confidence for matchup %s: %w", p.MatchupID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit confidence picks: %w", err)
+	}
+	for _, p := range picks {
+		invalidateConsensus(p.MatchupID, event_id)
+	}
+	return nil
+}
+
+// GetConfidenceStandings ranks every user with a confidence assignment for the event by points
+// scored on graded picks. possible_left is the points still in play on pending picks
+func GetConfidenceStandings(event_id string) ([]ConfidenceStanding, error) {
+	sqlStatement := `SELECT
+			RANK() OVER (ORDER BY s.points DESC) AS rank,
+			s.user_id, u.username, s.points, s.possible_left, s.correct, s.graded, s.assigned
+		FROM (
+			SELECT user_id,
+				COALESCE(SUM(confidence) FILTER (WHERE pick_result = $2), 0) AS points,
+				COALESCE(SUM(confidence) FILTER (WHERE pick_result = $3), 0) AS possible_left,
+				COUNT(*) FILTER (WHERE pick_result = $2) AS correct,
+				COUNT(*) FILTER (WHERE pick_result IN ($2, $4)) AS graded,
+				COUNT(*) AS assigned
+			FROM public.picks
+			WHERE event_id = $1 AND confidence IS NOT NULL
+			GROUP BY user_id
+		) s
+		JOIN public.users u ON u.user_id = s.user_id
+		ORDER BY rank, u.username;`
+
+	rows, err := usersDb.Query(sqlStatement, event_id, PickResultCorrect, PickResultPending, PickResultIncorrect)
+	if err != nil {
+		return nil, fmt.Errorf("error querying confidence standings for event %s: %w", event_id, err)
+	}
+	defer rows.Close()
+
+	standings := []ConfidenceStanding{}
+	for rows.Next() {
+		var s ConfidenceStanding
+		if err := rows.Scan(&s.Rank, &s.UserID, &s.Username, &s.Points, &s.PossibleLeft, &s.CorrectPicks, &s.GradedPicks, &s.AssignedPicks); err != nil {
+			return nil, fmt.Errorf("error scanning confidence standing row: %w", err)
+		}
+		standings = append(standings, s)
+	}
+	return standings, rows.Err()
+}
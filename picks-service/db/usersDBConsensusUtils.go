@@ -0,0 +1,190 @@
+// usersDBConsensusUtils aggregates picks into community consensus without exposing who picked what
+package db
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// how long a consensus stays cached if no pick on it changes. writes invalidate it right away
+const consensusCacheTTL = 5 * time.Minute
+
+// consensusCacheSize caps the cached entries, since the ids come straight from unauthenticated requests
+const consensusCacheSize = 1000
+
+type FighterConsensus struct {
+	FighterID  string  `json:"fighter_id"`
+	Picks      int     `json:"picks"`
+	Percentage float64 `json:"percentage"`
+}
+
+type MatchupConsensus struct {
+	MatchupID  string             `json:"matchup_id"`
+	EventID    string             `json:"event_id"`
+	TotalPicks int                `json:"total_picks"`
+	Fighters   []FighterConsensus `json:"fighters"`
+}
+
+type EventConsensus struct {
+	EventID    string             `json:"event_id"`
+	TotalPicks int                `json:"total_picks"`
+	Matchups   []MatchupConsensus `json:"matchups"`
+}
+
+type consensusEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// consensusCache holds computed consensus keyed by matchup and event id
+type consensusCache struct {
+	mu      sync.Mutex
+	entries map[string]consensusEntry
+}
+
+var consensus = &consensusCache{entries: make(map[string]consensusEntry)}
+
+func (c *consensusCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// set stores a value, first sweeping expired entries and then evicting the one closest to expiring when full
+func (c *consensusCache) set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= consensusCacheSize {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= consensusCacheSize {
+			var oldest string
+			for k, entry := range c.entries {
+				if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+					oldest = k
+				}
+			}
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[key] = consensusEntry{value: value, expires: now.Add(consensusCacheTTL)}
+}
+
+func (c *consensusCache) delete(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
+
+func (c *consensusCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]consensusEntry)
+}
+
+func matchupConsensusKey(matchup_id string) string { return "matchup:" + matchup_id }
+func eventConsensusKey(event_id string) string     { return "event:" + event_id }
+
+// invalidateConsensus drops the cached consensus of a matchup and its event after a pick on it changes
+func invalidateConsensus(matchup_id string, event_id string) {
+	consensus.delete(matchupConsensusKey(matchup_id), eventConsensusKey(event_id))
+}
+
+// GetMatchupConsensus returns how many users picked each fighter of a matchup
+func GetMatchupConsensus(matchup_id string) (MatchupConsensus, error) {
+	key := matchupConsensusKey(matchup_id)
+	if cached, ok := consensus.get(key); ok {
+		return cached.(MatchupConsensus), nil
+	}
+
+	matchups, err := queryConsensus("matchup_id = $1", matchup_id)
+	if err != nil {
+		return MatchupConsensus{}, err
+	}
+
+	result := MatchupConsensus{MatchupID: matchup_id, Fighters: []FighterConsensus{}}
+	//ids nobody picked aren't cached, so made up ids can't push real entries out
+	if len(matchups) > 0 {
+		result = matchups[0]
+		consensus.set(key, result)
+	}
+	return result, nil
+}
+
+// GetEventConsensus returns the consensus of every picked matchup of an event
+func GetEventConsensus(event_id string) (EventConsensus, error) {
+	key := eventConsensusKey(event_id)
+	if cached, ok := consensus.get(key); ok {
+		return cached.(EventConsensus), nil
+	}
+
+	matchups, err := queryConsensus("event_id = $1", event_id)
+	if err != nil {
+		return EventConsensus{}, err
+	}
+
+	result := EventConsensus{EventID: event_id, Matchups: matchups}
+	for _, m := range matchups {
+		result.TotalPicks += m.TotalPicks
+	}
+	if len(matchups) > 0 {
+		consensus.set(key, result)
+	}
+	return result, nil
+}
+
+// queryConsensus counts picks per fighter for the matchups matched by where. cancelled picks were on
+// a fighter who is no longer in the bout, so they're left out
+func queryConsensus(where string, arg string) ([]MatchupConsensus, error) {
+	sqlStatement := `SELECT matchup_id, event_id, selection_fighter_id, COUNT(*) FROM public.picks
+		WHERE ` + where + ` AND pick_result <> $2 GROUP BY matchup_id, event_id, selection_fighter_id
+		ORDER BY matchup_id, COUNT(*) DESC, selection_fighter_id;`
+	rows, err := usersDb.Query(sqlStatement, arg, PickResultCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("error querying pick consensus: %w", err)
+	}
+	defer rows.Close()
+
+	matchups := []MatchupConsensus{}
+	index := make(map[string]int)
+	for rows.Next() {
+		var matchupId, eventId string
+		var fighter FighterConsensus
+		if err := rows.Scan(&matchupId, &eventId, &fighter.FighterID, &fighter.Picks); err != nil {
+			return nil, fmt.Errorf("error scanning pick consensus row: %w", err)
+		}
+
+		i, ok := index[matchupId]
+		if !ok {
+			i = len(matchups)
+			index[matchupId] = i
+			matchups = append(matchups, MatchupConsensus{MatchupID: matchupId, EventID: eventId})
+		}
+		matchups[i].TotalPicks += fighter.Picks
+		matchups[i].Fighters = append(matchups[i].Fighters, fighter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pick consensus rows: %w", err)
+	}
+
+	for i := range matchups {
+		for j := range matchups[i].Fighters {
+			matchups[i].Fighters[j].Percentage = 100 * float64(matchups[i].Fighters[j].Picks) / float64(matchups[i].TotalPicks)
+		}
+	}
+	return matchups, nil
+}
@@ -0,0 +1,119 @@
+// usersDBExportUtils streams a user's whole pick history for export through a server-side cursor,
+// so long histories never sit in memory at once
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ExportFetchSize is how many rows each FETCH pulls from the export cursor
+const ExportFetchSize = 500
+
+// ExportedPick is a pick with the names, odds and result needed to read it on its own
+type ExportedPick struct {
+	PickID      int        `json:"pick_id"`
+	EventID     string     `json:"event_id"`
+	EventName   *string    `json:"event_name"`
+	EventDate   *time.Time `json:"event_date"`
+	MatchupID   string     `json:"matchup_id"`
+	CardType    *string    `json:"card_type"`
+	WeightClass *string    `json:"weight_class"`
+	SelectionID string     `json:"selection_fighter_id"`
+	Selection   *string    `json:"selection_name"`
+	OpponentID  *string    `json:"opponent_fighter_id"`
+	Opponent    *string    `json:"opponent_name"`
+	OddsAtPick  *int       `json:"odds_at_pick"`
+	ClosingOdds *int       `json:"closing_odds"`
+	Confidence  *int       `json:"confidence"`
+	Probability *float64   `json:"probability"`
+	PickResult  string     `json:"pick_result"`
+	Outcome     *string    `json:"outcome"`
+	WinnerID    *string    `json:"winner_fighter_id"`
+	Method      *string    `json:"method"`
+	Round       *int       `json:"round"`
+	CreatedAt   time.Time  `json:"created_at"`
+	GradedAt    *time.Time `json:"graded_at"`
+}
+
+// StreamPicksForUser calls each for every pick of the user, oldest first. Rows are fetched in batches of
+// ExportFetchSize from a cursor declared inside a read-only transaction; an error from each stops the export
+func StreamPicksForUser(ctx context.Context, userID int, each func(ExportedPick) error) error {
+	tx, err := usersDb.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("unable to start export transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	//the opponent is whichever synced fighter wasn't picked
+	sqlDeclare := `DECLARE pick_export NO SCROLL CURSOR FOR
+		SELECT p.pick_id, p.event_id, ms.event_name, ed.event_date, p.matchup_id, ms.card_type, ms.weight_class,
+			p.selection_fighter_id,
+			CASE p.selection_fighter_id WHEN ms.fighter1_id THEN ms.fighter1_name WHEN ms.fighter2_id THEN ms.fighter2_name END,
+			CASE p.selection_fighter_id WHEN ms.fighter1_id THEN ms.fighter2_id WHEN ms.fighter2_id THEN ms.fighter1_id END,
+			CASE p.selection_fighter_id WHEN ms.fighter1_id THEN ms.fighter2_name WHEN ms.fighter2_id THEN ms.fighter1_name END,
+			p.odds_at_pick, p.closing_odds, p.confidence, p.probability, p.pick_result,
+			mr.outcome, mr.winner_fighter_id, mr.method, mr.round, p.created_at, mr.graded_at
+		FROM public.picks p
+		LEFT JOIN public.matchup_schedule ms ON ms.matchup_id = p.matchup_id
+		LEFT JOIN public.event_dates ed ON ed.event_id = p.event_id
+		LEFT JOIN public.matchup_results mr ON mr.matchup_id = p.matchup_id
+		WHERE p.user_id = $1
+		ORDER BY p.created_at, p.pick_id;`
+	if _, err := tx.ExecContext(ctx, sqlDeclare, userID); err != nil {
+		return fmt.Errorf("unable to open export cursor for user %d: %w", userID, err)
+	}
+
+	sqlFetch := fmt.Sprintf("FETCH FORWARD %d FROM pick_export;", ExportFetchSize)
+	for {
+		fetched, err := fetchExportBatch(ctx, tx, sqlFetch, each)
+		if err != nil {
+			return err
+		}
+		if fetched < ExportFetchSize {
+			break
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "CLOSE pick_export;"); err != nil {
+		return fmt.Errorf("unable to close export cursor: %w", err)
+	}
+	return tx.Commit()
+}
+
+// fetchExportBatch reads one FETCH worth of rows and returns how many there were
+func fetchExportBatch(ctx context.Context, tx *sql.Tx, sqlFetch string, each func(ExportedPick) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, sqlFetch)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching from export cursor: %w", err)
+	}
+	defer rows.Close()
+
+	fetched := 0
+	for rows.Next() {
+		var p ExportedPick
+		var pickResult sql.NullString
+		err := rows.Scan(&p.PickID, &p.EventID, &p.EventName, &p.EventDate, &p.MatchupID, &p.CardType, &p.WeightClass,
+			&p.SelectionID, &p.Selection, &p.OpponentID, &p.Opponent,
+			&p.OddsAtPick, &p.ClosingOdds, &p.Confidence, &p.Probability, &pickResult,
+			&p.Outcome, &p.WinnerID, &p.Method, &p.Round, &p.CreatedAt, &p.GradedAt)
+		if err != nil {
+			return fetched, fmt.Errorf("error scanning exported pick row: %w", err)
+		}
+		p.PickResult = pickResult.String
+		if p.PickResult == "" {
+			p.PickResult = PickResultPending
+		}
+		fetched++
+
+		if err := each(p); err != nil {
+			return fetched, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fetched, fmt.Errorf("error iterating export cursor: %w", err)
+	}
+	return fetched, nil
+}
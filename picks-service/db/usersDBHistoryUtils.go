@@ -0,0 +1,190 @@
+// usersDBHistoryUtils keeps the append-only audit trail of pick changes
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// where a pick change came from
+const (
+	PickSourceSingle     = "single"
+	PickSourceBatch      = "batch"
+	PickSourceConfidence = "confidence"
+	PickSourceAdmin      = "admin"
+)
+
+// change_type of a pick_history row. migrate and cancel rows come from rebooks and scrapped bouts and keep
+// the selection the pick already had
+const (
+	PickChangeInsert  = "insert"
+	PickChangeUpdate  = "update"
+	PickChangeMigrate = "migrate"
+	PickChangeCancel  = "cancel"
+)
+
+// PickMeta describes the request a pick write came from. OverrideLock lets admins write past the lock time
+type PickMeta struct {
+	OverrideLock bool
+	Source       string
+	IP           string
+	UserAgent    string
+}
+
+type PickChange struct {
+	HistoryID         int       `json:"history_id"`
+	PickID            int       `json:"pick_id"`
+	UserID            int       `json:"user_id"`
+	MatchupID         string    `json:"matchup_id"`
+	EventID           string    `json:"event_id"`
+	PreviousSelection *string   `json:"previous_selection_fighter_id"`
+	NewSelection      string    `json:"new_selection_fighter_id"`
+	ChangeType        string    `json:"change_type"`
+	Source            string    `json:"source"`
+	SourceIP          string    `json:"source_ip"`
+	UserAgent         string    `json:"user_agent"`
+	ChangedAt         time.Time `json:"changed_at"`
+}
+
+// UserPickHistory is one page of a user's pick changes plus how often they switched sides over all of them
+type UserPickHistory struct {
+	Page[PickChange]
+	UserID          int     `json:"user_id"`
+	EventID         string  `json:"event_id,omitempty"`
+	Switches        int     `json:"switches"`
+	MatchupsChanged int     `json:"matchups_changed"`
+	PicksMade       int     `json:"picks_made"`
+	SwitchRate      float64 `json:"switch_rate"`
+}
+
+const pickChangeColumns = `history_id, pick_id, user_id, matchup_id, event_id, previous_selection_fighter_id,
+	new_selection_fighter_id, change_type, source, COALESCE(source_ip, ''), COALESCE(user_agent, ''), changed_at`
+
+// recordPickChange appends a row to pick_history for a pick the user inserted or switched
+func recordPickChange(ex dbExecutor, pick_id int, user_id string, matchup_id string, event_id string, inserted bool, previous string, selection string, meta PickMeta) error {
+	changeType := PickChangeUpdate
+	if inserted {
+		changeType = PickChangeInsert
+	}
+	var previousSelection sql.NullString
+	if previous != "" {
+		previousSelection = sql.NullString{String: previous, Valid: true}
+	}
+
+	source := meta.Source
+	if source == "" {
+		source = PickSourceSingle
+	}
+
+	sqlStatement := `INSERT INTO public.pick_history (pick_id, user_id, matchup_id, event_id, previous_selection_fighter_id,
+		new_selection_fighter_id, change_type, source, source_ip, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''));`
+	_, err := ex.ExecContext(context.Background(), sqlStatement, pick_id, user_id, matchup_id, event_id, previousSelection,
+		selection, changeType, source, meta.IP, meta.UserAgent)
+	if err != nil {
+		return fmt.Errorf("unable to record pick history: %w", err)
+	}
+	return nil
+}
+
+// recordPickChanges runs sqlChanged, an UPDATE on picks returning pick_id, user_id, matchup_id, event_id and
+// selection_fighter_id, and appends a changeType row to pick_history for every pick it touched. It returns how
+// many picks changed
+func recordPickChanges(ex dbExecutor, changeType string, sqlChanged string, args ...interface{}) (int, error) {
+	sqlStatement := fmt.Sprintf(`WITH changed AS (%s)
+		INSERT INTO public.pick_history (pick_id, user_id, matchup_id, event_id, previous_selection_fighter_id,
+			new_selection_fighter_id, change_type, source)
+		SELECT pick_id, user_id, matchup_id, event_id, selection_fighter_id, selection_fighter_id, $%d, $%d FROM changed;`,
+		sqlChanged, len(args)+1, len(args)+2)
+	res, err := ex.ExecContext(context.Background(), sqlStatement, append(args, changeType, PickSourceAdmin)...)
+	if err != nil {
+		return 0, err
+	}
+	changed, _ := res.RowsAffected()
+	return int(changed), nil
+}
+
+// GetPickHistoryForUser returns a page of a user's pick changes oldest first, optionally limited to one event.
+// The switch stats cover every change, not just the page
+func GetPickHistoryForUser(user_id int, event_id string, q ListQuery) (UserPickHistory, error) {
+	history := UserPickHistory{UserID: user_id, EventID: event_id}
+	limit, offset, err := q.normalize(listingUserHistory, DefaultPickPageSize, MaxPickPageSize)
+	if err != nil {
+		return history, err
+	}
+
+	var total int
+	sqlStats := `SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE change_type = $3),
+			COUNT(*) FILTER (WHERE change_type = $4),
+			COUNT(DISTINCT matchup_id) FILTER (WHERE change_type = $4)
+		FROM public.pick_history WHERE user_id = $1 AND ($2 = '' OR event_id = $2);`
+	err = usersDb.QueryRow(sqlStats, user_id, event_id, PickChangeInsert, PickChangeUpdate).Scan(&total,
+		&history.PicksMade, &history.Switches, &history.MatchupsChanged)
+	if err != nil {
+		return history, fmt.Errorf("error counting pick history for user %d: %w", user_id, err)
+	}
+	if history.PicksMade > 0 {
+		history.SwitchRate = float64(history.MatchupsChanged) / float64(history.PicksMade)
+	}
+
+	sqlStatement := "SELECT " + pickChangeColumns + ` FROM public.pick_history
+		WHERE user_id = $1 AND ($2 = '' OR event_id = $2) ORDER BY changed_at, history_id LIMIT $3 OFFSET $4;`
+	changes, err := queryPickChanges(sqlStatement, user_id, event_id, limit, offset)
+	if err != nil {
+		return history, err
+	}
+	history.Page = finishListPage(listingUserHistory, changes, offset, total)
+	return history, nil
+}
+
+// GetPickHistoryForMatchup returns a page of the pick changes made on a matchup, oldest first
+func GetPickHistoryForMatchup(matchup_id string, q ListQuery) (Page[PickChange], error) {
+	limit, offset, err := q.normalize(listingMatchupHistory, DefaultPickPageSize, MaxPickPageSize)
+	if err != nil {
+		return Page[PickChange]{}, err
+	}
+
+	var total int
+	if err := usersDb.QueryRow("SELECT COUNT(*) FROM public.pick_history WHERE matchup_id = $1;", matchup_id).Scan(&total); err != nil {
+		return Page[PickChange]{}, fmt.Errorf("error counting pick history for matchup %s: %w", matchup_id, err)
+	}
+
+	sqlStatement := "SELECT " + pickChangeColumns + ` FROM public.pick_history
+		WHERE matchup_id = $1 ORDER BY changed_at, history_id LIMIT $2 OFFSET $3;`
+	changes, err := queryPickChanges(sqlStatement, matchup_id, limit, offset)
+	if err != nil {
+		return Page[PickChange]{}, err
+	}
+	return finishListPage(listingMatchupHistory, changes, offset, total), nil
+}
+
+func queryPickChanges(sqlStatement string, args ...interface{}) ([]PickChange, error) {
+	rows, err := usersDb.Query(sqlStatement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying pick history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []PickChange{}
+	for rows.Next() {
+		var c PickChange
+		var previous sql.NullString
+		err := rows.Scan(&c.HistoryID, &c.PickID, &c.UserID, &c.MatchupID, &c.EventID, &previous,
+			&c.NewSelection, &c.ChangeType, &c.Source, &c.SourceIP, &c.UserAgent, &c.ChangedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning pick history row: %w", err)
+		}
+		if previous.Valid {
+			c.PreviousSelection = &previous.String
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pick history rows: %w", err)
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,237 @@
+// usersDBLeaderboardUtils ranks users by graded picks
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type LeaderboardScope string
+
+const (
+	LeaderboardAllTime   LeaderboardScope = "all"
+	LeaderboardEvent     LeaderboardScope = "event"
+	LeaderboardSeason    LeaderboardScope = "season"
+	LeaderboardRolling90 LeaderboardScope = "90d"
+)
+
+const (
+	DefaultLeaderboardLimit = 50
+	MaxLeaderboardLimit     = 200
+)
+
+type LeaderboardQuery struct {
+	Scope   LeaderboardScope
+	EventID string
+	// SeasonID picks the season for the season scope; 0 means the current season
+	SeasonID int
+	// LeagueID limits the leaderboard to members of a league when non-zero
+	LeagueID int
+	Limit    int
+	Offset   int
+	// Cursor is the NextCursor of the previous page and overrides Offset when set
+	Cursor string
+}
+
+type LeaderboardEntry struct {
+	Rank         int     `json:"rank"`
+	UserID       int     `json:"user_id"`
+	Username     string  `json:"username"`
+	CorrectPicks int     `json:"correct_picks"`
+	TotalPicks   int     `json:"total_picks"`
+	Accuracy     float64 `json:"accuracy"`
+}
+
+// Leaderboard is one page of ranked users. Total counts every ranked user
+type Leaderboard struct {
+	Scope    LeaderboardScope `json:"scope"`
+	EventID  string           `json:"event_id,omitempty"`
+	SeasonID int              `json:"season_id,omitempty"`
+	Season   *Season          `json:"season,omitempty"`
+	LeagueID int              `json:"league_id,omitempty"`
+	Page[LeaderboardEntry]
+	Limit  int               `json:"limit"`
+	Offset int               `json:"offset"`
+	Me     *LeaderboardEntry `json:"me"`
+}
+
+// standingsSQL returns a query producing (user_id, correct, total) for the scope, plus its arguments.
+// season is only used by the season scope
+func standingsSQL(q LeaderboardQuery, season *Season) (string, []interface{}, error) {
+	//all-time standings come straight from the counters grading maintains on users
+	if q.Scope == LeaderboardAllTime {
+		return `SELECT user_id, COALESCE(total_correct_picks, 0) AS correct, COALESCE(total_picks, 0) AS total
+			FROM public.users WHERE COALESCE(total_picks, 0) > 0`, nil, nil
+	}
+
+	args := []interface{}{PickResultCorrect, PickResultIncorrect}
+	if q.Scope == LeaderboardSeason {
+		if season == nil {
+			return "", nil, fmt.Errorf("season is required for the season leaderboard")
+		}
+		//closed seasons are served from their frozen standings
+		if season.Status == SeasonClosed {
+			standings, args := archivedSeasonStandingsSQL(*season)
+			return standings, args, nil
+		}
+		standings, args := liveSeasonStandingsSQL(*season, args)
+		return standings, args, nil
+	}
+
+	var filter string
+	switch q.Scope {
+	case LeaderboardEvent:
+		if q.EventID == "" {
+			return "", nil, fmt.Errorf("eventId is required for the event leaderboard")
+		}
+		args = append(args, q.EventID)
+		filter = fmt.Sprintf("p.event_id = $%d", len(args))
+	case LeaderboardRolling90:
+		filter = "mr.graded_at >= CURRENT_TIMESTAMP - INTERVAL '90 days'"
+	default:
+		return "", nil, fmt.Errorf("unknown leaderboard scope %q", q.Scope)
+	}
+
+	return fmt.Sprintf(`SELECT p.user_id, COUNT(*) FILTER (WHERE p.pick_result = $1) AS correct, COUNT(*) AS total
+		FROM public.picks p
+		JOIN public.matchup_results mr ON mr.matchup_id = p.matchup_id
+		WHERE p.pick_result IN ($1, $2) AND %s
+		GROUP BY p.user_id`, filter), args, nil
+}
+
+// rankedSQL wraps the standings with ranks. Users are ranked by correct picks, ties broken by fewer
+// picks made (higher accuracy); users still tied share a rank and are listed by username
+func rankedSQL(standings string) string {
+	return fmt.Sprintf(`WITH standings AS (%s),
+		ranked AS (
+			SELECT
+				RANK() OVER (ORDER BY s.correct DESC, s.total ASC) AS rank,
+				s.user_id, u.username, s.correct, s.total,
+				COUNT(*) OVER () AS total_users
+			FROM standings s
+			JOIN public.users u ON u.user_id = s.user_id
+		)`, standings)
+}
+
+// GetLeaderboard returns one page of the leaderboard. If userID is non-zero the user's own entry is
+// returned in Me regardless of the page
+func GetLeaderboard(q LeaderboardQuery, userID int) (Leaderboard, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLeaderboardLimit
+	}
+	if q.Limit > MaxLeaderboardLimit {
+		q.Limit = MaxLeaderboardLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.Cursor != "" {
+		_, offset, err := ListQuery{Limit: q.Limit, Cursor: q.Cursor}.normalize(listingLeaderboard, DefaultLeaderboardLimit, MaxLeaderboardLimit)
+		if err != nil {
+			return Leaderboard{}, err
+		}
+		q.Offset = offset
+	}
+
+	board := Leaderboard{Scope: q.Scope, EventID: q.EventID, LeagueID: q.LeagueID, Limit: q.Limit, Offset: q.Offset}
+
+	if q.Scope == LeaderboardSeason {
+		var season Season
+		var err error
+		if q.SeasonID == 0 {
+			season, err = GetCurrentSeason()
+		} else {
+			season, err = GetSeason(q.SeasonID)
+		}
+		if err != nil {
+			return board, err
+		}
+		board.SeasonID = season.SeasonID
+		board.Season = &season
+	}
+
+	standings, args, err := standingsSQL(q, board.Season)
+	if err != nil {
+		return board, err
+	}
+	if q.LeagueID != 0 {
+		args = append(args, q.LeagueID)
+		standings = fmt.Sprintf(`SELECT st.* FROM (%s) st
+			WHERE st.user_id IN (SELECT user_id FROM public.league_members WHERE league_id = $%d)`, standings, len(args))
+	}
+	return queryLeaderboard(board, rankedSQL(standings), args, userID)
+}
+
+// queryLeaderboard fills a page and the requesting user's entry from a ranked CTE
+func queryLeaderboard(board Leaderboard, ranked string, args []interface{}, userID int) (Leaderboard, error) {
+	pageArgs := append(append([]interface{}{}, args...), board.Limit, board.Offset)
+	sqlPage := fmt.Sprintf(`%s
+		SELECT rank, user_id, username, correct, total, total_users FROM ranked
+		ORDER BY rank, username
+		LIMIT $%d OFFSET $%d;`, ranked, len(args)+1, len(args)+2)
+
+	rows, err := usersDb.Query(sqlPage, pageArgs...)
+	if err != nil {
+		return board, fmt.Errorf("error querying %s leaderboard: %w", board.Scope, err)
+	}
+	defer rows.Close()
+
+	var entries []LeaderboardEntry
+	var total int
+	for rows.Next() {
+		var entry LeaderboardEntry
+		if err := rows.Scan(&entry.Rank, &entry.UserID, &entry.Username, &entry.CorrectPicks, &entry.TotalPicks, &total); err != nil {
+			return board, fmt.Errorf("error scanning leaderboard row: %w", err)
+		}
+		entry.Accuracy = accuracy(entry.CorrectPicks, entry.TotalPicks)
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return board, fmt.Errorf("error reading leaderboard rows: %w", err)
+	}
+	board.Page = finishListPage(listingLeaderboard, entries, board.Offset, total)
+
+	if userID == 0 {
+		return board, nil
+	}
+
+	meArgs := append(append([]interface{}{}, args...), userID)
+	sqlMe := fmt.Sprintf(`%s
+		SELECT rank, user_id, username, correct, total, total_users FROM ranked WHERE user_id = $%d;`, ranked, len(args)+1)
+
+	var me LeaderboardEntry
+	err = usersDb.QueryRow(sqlMe, meArgs...).Scan(&me.Rank, &me.UserID, &me.Username, &me.CorrectPicks, &me.TotalPicks, &board.Total)
+	if err == sql.ErrNoRows {
+		return board, nil
+	}
+	if err != nil {
+		return board, fmt.Errorf("error retrieving leaderboard rank for user %d: %w", userID, err)
+	}
+	me.Accuracy = accuracy(me.CorrectPicks, me.TotalPicks)
+	board.Me = &me
+
+	return board, nil
+}
+
+func accuracy(correct int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(correct) / float64(total)
+}
+
+// ParseLeaderboardScope accepts the scope names used by the API, defaulting to all-time
+func ParseLeaderboardScope(scope string) (LeaderboardScope, error) {
+	switch LeaderboardScope(strings.ToLower(scope)) {
+	case "", LeaderboardAllTime:
+		return LeaderboardAllTime, nil
+	case LeaderboardEvent:
+		return LeaderboardEvent, nil
+	case LeaderboardSeason:
+		return LeaderboardSeason, nil
+	case LeaderboardRolling90:
+		return LeaderboardRolling90, nil
+	}
+	return "", fmt.Errorf("unknown leaderboard scope %q", scope)
+}
@@ -0,0 +1,423 @@
+// usersDBLeaguesUtils handles private leagues: membership, admins and invite codes
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// league member roles
+const (
+	LeagueRoleAdmin  = "admin"
+	LeagueRoleMember = "member"
+)
+
+const MaxLeagueNameLength = 100
+
+var (
+	ErrLeagueNotFound   = errors.New("league not found")
+	ErrNotLeagueMember  = errors.New("not a member of this league")
+	ErrNotLeagueAdmin   = errors.New("only league admins can do this")
+	ErrInvalidInvite    = errors.New("invite code is invalid, expired or used up")
+	ErrLastLeagueAdmin  = errors.New("the last admin can't leave while other members remain; promote someone first")
+	ErrInvalidLeagueArg = errors.New("invalid league request")
+)
+
+type League struct {
+	LeagueID  int    `json:"league_id"`
+	Name      string `json:"name"`
+	CreatedBy int    `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+	Role      string `json:"role,omitempty"`
+	Members   int    `json:"members"`
+}
+
+type LeagueMember struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	JoinedAt string `json:"joined_at"`
+}
+
+type LeagueInvite struct {
+	InviteCode string     `json:"invite_code"`
+	LeagueID   int        `json:"league_id"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	MaxUses    *int       `json:"max_uses"`
+	Uses       int        `json:"uses"`
+}
+
+// newInviteCode returns a random 10 character code that's easy to read out loud (no 0/1/8/9 confusion in base32)
+func newInviteCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate invite code: %w", err)
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+// normalizeInviteCode undoes what copying a code by hand tends to do to it: stray spaces and lower case
+func normalizeInviteCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CreateLeague creates a league with the creator as its first admin
+func CreateLeague(name string, userID int) (League, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > MaxLeagueNameLength {
+		return League{}, fmt.Errorf("%w: name must be between 1 and %d characters", ErrInvalidLeagueArg, MaxLeagueNameLength)
+	}
+
+	tx, err := usersDb.BeginTx(context.Background(), nil)
+	if err != nil {
+		return League{}, fmt.Errorf("unable to start league transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	league := League{Name: name, CreatedBy: userID, Role: LeagueRoleAdmin, Members: 1}
+	sqlLeague := "INSERT INTO public.leagues (name, created_by) VALUES ($1, $2) RETURNING league_id, created_at;"
+	if err := tx.QueryRow(sqlLeague, name, userID).Scan(&league.LeagueID, &league.CreatedAt); err != nil {
+		return League{}, fmt.Errorf("unable to create league: %w", err)
+	}
+
+	sqlMember := "INSERT INTO public.league_members (league_id, user_id, role) VALUES ($1, $2, $3);"
+	if _, err := tx.ExecContext(context.Background(), sqlMember, league.LeagueID, userID, LeagueRoleAdmin); err != nil {
+		return League{}, fmt.Errorf("unable to add league creator: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return League{}, fmt.Errorf("unable to commit league: %w", err)
+	}
+	return league, nil
+}
+
+// GetLeagueRole returns the user's role in the league, ErrLeagueNotFound or ErrNotLeagueMember
+func GetLeagueRole(leagueID int, userID int) (string, error) {
+	var role sql.NullString
+	sqlStatement := `SELECT lm.role FROM public.leagues l
+		LEFT JOIN public.league_members lm ON lm.league_id = l.league_id AND lm.user_id = $2
+		WHERE l.league_id = $1;`
+	err := usersDb.QueryRow(sqlStatement, leagueID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", ErrLeagueNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("error retrieving league role: %w", err)
+	}
+	if !role.Valid {
+		return "", ErrNotLeagueMember
+	}
+	return role.String, nil
+}
+
+// RequireLeagueAdmin returns nil only if the user is an admin of the league
+func RequireLeagueAdmin(leagueID int, userID int) error {
+	role, err := GetLeagueRole(leagueID, userID)
+	if err != nil {
+		return err
+	}
+	if role != LeagueRoleAdmin {
+		return ErrNotLeagueAdmin
+	}
+	return nil
+}
+
+// CreateLeagueInvite creates an invite code. expiresAt and maxUses are optional (nil / 0 means unlimited)
+func CreateLeagueInvite(leagueID int, userID int, expiresAt *time.Time, maxUses int) (LeagueInvite, error) {
+	if err := RequireLeagueAdmin(leagueID, userID); err != nil {
+		return LeagueInvite{}, err
+	}
+	if maxUses < 0 {
+		return LeagueInvite{}, fmt.Errorf("%w: maxUses can't be negative", ErrInvalidLeagueArg)
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		return LeagueInvite{}, err
+	}
+
+	invite := LeagueInvite{InviteCode: code, LeagueID: leagueID, ExpiresAt: expiresAt}
+	if maxUses > 0 {
+		invite.MaxUses = &maxUses
+	}
+
+	sqlInsert := "INSERT INTO public.league_invites (invite_code, league_id, created_by, expires_at, max_uses) VALUES ($1, $2, $3, $4, $5);"
+	if _, err := usersDb.ExecContext(context.Background(), sqlInsert, code, leagueID, userID, expiresAt, invite.MaxUses); err != nil {
+		return LeagueInvite{}, fmt.Errorf("unable to create invite for league %d: %w", leagueID, err)
+	}
+	return invite, nil
+}
+
+// RevokeLeagueInvite disables an invite code
+func RevokeLeagueInvite(code string, userID int) error {
+	code = normalizeInviteCode(code)
+	var leagueID int
+	err := usersDb.QueryRow("SELECT league_id FROM public.league_invites WHERE invite_code = $1;", code).Scan(&leagueID)
+	if err == sql.ErrNoRows {
+		return ErrInvalidInvite
+	}
+	if err != nil {
+		return fmt.Errorf("error retrieving invite: %w", err)
+	}
+
+	if err := RequireLeagueAdmin(leagueID, userID); err != nil {
+		return err
+	}
+
+	_, err = usersDb.ExecContext(context.Background(), "UPDATE public.league_invites SET revoked = true WHERE invite_code = $1;", code)
+	if err != nil {
+		return fmt.Errorf("unable to revoke invite: %w", err)
+	}
+	return nil
+}
+
+// JoinLeague adds the user to the league behind an invite code. Joining a league you're already in is a no-op
+func JoinLeague(code string, userID int) (League, error) {
+	code = normalizeInviteCode(code)
+	tx, err := usersDb.BeginTx(context.Background(), nil)
+	if err != nil {
+		return League{}, fmt.Errorf("unable to start join transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	//lock the invite row so max_uses can't be exceeded by concurrent joins
+	var leagueID, uses int
+	var maxUses sql.NullInt64
+	var expiresAt sql.NullTime
+	var revoked bool
+	sqlInvite := "SELECT league_id, uses, max_uses, expires_at, revoked FROM public.league_invites WHERE invite_code = $1 FOR UPDATE;"
+	err = tx.QueryRow(sqlInvite, code).Scan(&leagueID, &uses, &maxUses, &expiresAt, &revoked)
+	if err == sql.ErrNoRows {
+		return League{}, ErrInvalidInvite
+	}
+	if err != nil {
+		return League{}, fmt.Errorf("error retrieving invite: %w", err)
+	}
+
+	if revoked || (expiresAt.Valid && time.Now().After(expiresAt.Time)) || (maxUses.Valid && int64(uses) >= maxUses.Int64) {
+		return League{}, ErrInvalidInvite
+	}
+
+	sqlMember := "INSERT INTO public.league_members (league_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT (league_id, user_id) DO NOTHING;"
+	res, err := tx.ExecContext(context.Background(), sqlMember, leagueID, userID, LeagueRoleMember)
+	if err != nil {
+		return League{}, fmt.Errorf("unable to join league %d: %w", leagueID, err)
+	}
+
+	if added, _ := res.RowsAffected(); added > 0 {
+		sqlUses := "UPDATE public.league_invites SET uses = uses + 1 WHERE invite_code = $1;"
+		if _, err := tx.ExecContext(context.Background(), sqlUses, code); err != nil {
+			return League{}, fmt.Errorf("unable to update invite uses: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return League{}, fmt.Errorf("unable to commit join: %w", err)
+	}
+
+	return GetLeague(leagueID, userID)
+}
+
+// GetLeague returns a league as seen by one of its members
+func GetLeague(leagueID int, userID int) (League, error) {
+	role, err := GetLeagueRole(leagueID, userID)
+	if err != nil {
+		return League{}, err
+	}
+
+	league := League{LeagueID: leagueID, Role: role}
+	sqlStatement := `SELECT l.name, l.created_by, l.created_at, COUNT(lm.user_id)
+		FROM public.leagues l JOIN public.league_members lm ON lm.league_id = l.league_id
+		WHERE l.league_id = $1
+		GROUP BY l.league_id;`
+	if err := usersDb.QueryRow(sqlStatement, leagueID).Scan(&league.Name, &league.CreatedBy, &league.CreatedAt, &league.Members); err != nil {
+		return League{}, fmt.Errorf("error retrieving league %d: %w", leagueID, err)
+	}
+	return league, nil
+}
+
+// GetLeaguesForUser lists a page of the leagues the user belongs to, by name
+func GetLeaguesForUser(userID int, q ListQuery) (Page[League], error) {
+	limit, offset, err := q.normalize(listingLeagues, DefaultLeaderboardLimit, MaxLeaderboardLimit)
+	if err != nil {
+		return Page[League]{}, err
+	}
+
+	var total int
+	if err := usersDb.QueryRow("SELECT COUNT(*) FROM public.league_members WHERE user_id = $1;", userID).Scan(&total); err != nil {
+		return Page[League]{}, fmt.Errorf("error counting leagues for user %d: %w", userID, err)
+	}
+
+	sqlStatement := `SELECT l.league_id, l.name, l.created_by, l.created_at, me.role,
+			(SELECT COUNT(*) FROM public.league_members c WHERE c.league_id = l.league_id)
+		FROM public.league_members me
+		JOIN public.leagues l ON l.league_id = me.league_id
+		WHERE me.user_id = $1
+		ORDER BY l.name, l.league_id
+		LIMIT $2 OFFSET $3;`
+
+	rows, err := usersDb.Query(sqlStatement, userID, limit, offset)
+	if err != nil {
+		return Page[League]{}, fmt.Errorf("error querying leagues for user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	var leagues []League
+	for rows.Next() {
+		var league League
+		if err := rows.Scan(&league.LeagueID, &league.Name, &league.CreatedBy, &league.CreatedAt, &league.Role, &league.Members); err != nil {
+			return Page[League]{}, fmt.Errorf("error scanning league row: %w", err)
+		}
+		leagues = append(leagues, league)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[League]{}, fmt.Errorf("error iterating league rows: %w", err)
+	}
+	return finishListPage(listingLeagues, leagues, offset, total), nil
+}
+
+// GetLeagueMembers lists a page of the members of a league the requesting user belongs to
+func GetLeagueMembers(leagueID int, userID int, q ListQuery) (Page[LeagueMember], error) {
+	if _, err := GetLeagueRole(leagueID, userID); err != nil {
+		return Page[LeagueMember]{}, err
+	}
+	limit, offset, err := q.normalize(listingLeagueMembers, DefaultLeaderboardLimit, MaxLeaderboardLimit)
+	if err != nil {
+		return Page[LeagueMember]{}, err
+	}
+
+	var total int
+	if err := usersDb.QueryRow("SELECT COUNT(*) FROM public.league_members WHERE league_id = $1;", leagueID).Scan(&total); err != nil {
+		return Page[LeagueMember]{}, fmt.Errorf("error counting members of league %d: %w", leagueID, err)
+	}
+
+	sqlStatement := `SELECT lm.user_id, u.username, lm.role, lm.joined_at
+		FROM public.league_members lm JOIN public.users u ON u.user_id = lm.user_id
+		WHERE lm.league_id = $1
+		ORDER BY lm.role, u.username, lm.user_id
+		LIMIT $2 OFFSET $3;`
+
+	rows, err := usersDb.Query(sqlStatement, leagueID, limit, offset)
+	if err != nil {
+		return Page[LeagueMember]{}, fmt.Errorf("error querying members of league %d: %w", leagueID, err)
+	}
+	defer rows.Close()
+
+	var members []LeagueMember
+	for rows.Next() {
+		var member LeagueMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Role, &member.JoinedAt); err != nil {
+			return Page[LeagueMember]{}, fmt.Errorf("error scanning league member row: %w", err)
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[LeagueMember]{}, fmt.Errorf("error iterating league member rows: %w", err)
+	}
+	return finishListPage(listingLeagueMembers, members, offset, total), nil
+}
+
+// SetLeagueMemberRole promotes or demotes a member. Only admins can change roles
+func SetLeagueMemberRole(leagueID int, adminID int, targetID int, role string) error {
+	if role != LeagueRoleAdmin && role != LeagueRoleMember {
+		return fmt.Errorf("%w: role must be %q or %q", ErrInvalidLeagueArg, LeagueRoleAdmin, LeagueRoleMember)
+	}
+	if err := RequireLeagueAdmin(leagueID, adminID); err != nil {
+		return err
+	}
+
+	tx, err := usersDb.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("unable to start role transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(context.Background(), "UPDATE public.league_members SET role = $1 WHERE league_id = $2 AND user_id = $3;", role, leagueID, targetID)
+	if err != nil {
+		return fmt.Errorf("unable to update league role: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotLeagueMember
+	}
+
+	if err := ensureLeagueHasAdmin(tx, leagueID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveLeagueMember kicks a member out of the league. Only admins can remove other members
+func RemoveLeagueMember(leagueID int, adminID int, targetID int) error {
+	if err := RequireLeagueAdmin(leagueID, adminID); err != nil {
+		return err
+	}
+	return removeLeagueMember(leagueID, targetID)
+}
+
+// LeaveLeague removes the user from the league. The league is deleted when its last member leaves
+func LeaveLeague(leagueID int, userID int) error {
+	if _, err := GetLeagueRole(leagueID, userID); err != nil {
+		return err
+	}
+	return removeLeagueMember(leagueID, userID)
+}
+
+func removeLeagueMember(leagueID int, userID int) error {
+	tx, err := usersDb.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("unable to start leave transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(context.Background(), "DELETE FROM public.league_members WHERE league_id = $1 AND user_id = $2;", leagueID, userID)
+	if err != nil {
+		return fmt.Errorf("unable to remove member from league %d: %w", leagueID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotLeagueMember
+	}
+
+	var remaining int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM public.league_members WHERE league_id = $1;", leagueID).Scan(&remaining); err != nil {
+		return fmt.Errorf("error counting league members: %w", err)
+	}
+
+	if remaining == 0 {
+		if _, err := tx.ExecContext(context.Background(), "DELETE FROM public.leagues WHERE league_id = $1;", leagueID); err != nil {
+			return fmt.Errorf("unable to delete empty league %d: %w", leagueID, err)
+		}
+	} else if err := ensureLeagueHasAdmin(tx, leagueID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ensureLeagueHasAdmin fails the transaction if a change would leave members without an admin
+func ensureLeagueHasAdmin(tx *sql.Tx, leagueID int) error {
+	var admins int
+	sqlStatement := "SELECT COUNT(*) FROM public.league_members WHERE league_id = $1 AND role = $2;"
+	if err := tx.QueryRow(sqlStatement, leagueID, LeagueRoleAdmin).Scan(&admins); err != nil {
+		return fmt.Errorf("error counting league admins: %w", err)
+	}
+	if admins == 0 {
+		return ErrLastLeagueAdmin
+	}
+	return nil
+}
+
+// GetLeagueLeaderboard is GetLeaderboard limited to the league's members, for members only
+func GetLeagueLeaderboard(leagueID int, q LeaderboardQuery, userID int) (Leaderboard, error) {
+	if _, err := GetLeagueRole(leagueID, userID); err != nil {
+		return Leaderboard{}, err
+	}
+
+	q.LeagueID = leagueID
+	return GetLeaderboard(q, userID)
+}
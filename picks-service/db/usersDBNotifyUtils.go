@@ -0,0 +1,87 @@
+// usersDBNotifyUtils publishes pick and result changes over Postgres LISTEN/NOTIFY so every instance sees them
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// EventUpdatesChannel is the NOTIFY channel pick and result changes are sent on
+const EventUpdatesChannel = "pick_event_updates"
+
+// kinds of EventUpdate
+const (
+	UpdatePicks  = "picks"
+	UpdateResult = "result"
+)
+
+// EventUpdate is the NOTIFY payload. it only names what changed, listeners load the current state themselves,
+// which keeps payloads well under Postgres' 8000 byte limit
+type EventUpdate struct {
+	Type      string `json:"type"`
+	EventID   string `json:"event_id"`
+	MatchupID string `json:"matchup_id"`
+}
+
+// notifyEventUpdate queues a notification on the executor's transaction. Postgres delivers it on commit
+// and drops it on rollback, and identical notifications within one transaction are sent once
+func notifyEventUpdate(ex dbExecutor, update EventUpdate) error {
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("unable to encode event update: %w", err)
+	}
+
+	if _, err := ex.ExecContext(context.Background(), "SELECT pg_notify($1, $2);", EventUpdatesChannel, string(payload)); err != nil {
+		return fmt.Errorf("unable to notify event update: %w", err)
+	}
+	return nil
+}
+
+// ListenForEventUpdates calls handle for every EventUpdate sent by any instance, including this one.
+// Cached consensus is invalidated before handle runs so it always sees fresh counts. It blocks, so run it
+// in its own goroutine
+func ListenForEventUpdates(handle func(EventUpdate)) {
+	connStr := os.Getenv("USERS_CONNECTION_STRING")
+
+	listener := pq.NewListener(connStr, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Event update listener: %v", err)
+		}
+		if ev == pq.ListenerEventReconnected {
+			//notifications sent while disconnected are lost, so nothing cached can be trusted
+			consensus.clear()
+		}
+	})
+	if err := listener.Listen(EventUpdatesChannel); err != nil {
+		log.Printf("Unable to listen for event updates, live updates disabled: %v", err)
+		return
+	}
+
+	for {
+		select {
+		case n := <-listener.Notify:
+			//a nil notification follows a reconnect
+			if n == nil {
+				continue
+			}
+
+			var update EventUpdate
+			if err := json.Unmarshal([]byte(n.Extra), &update); err != nil {
+				log.Printf("Ignoring malformed event update %q: %v", n.Extra, err)
+				continue
+			}
+
+			invalidateConsensus(update.MatchupID, update.EventID)
+			handle(update)
+		case <-time.After(90 * time.Second):
+			//make sure the connection is still alive, pq reconnects if not
+			go listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// usersDBOddsUtils stores the odds picks were made at and scores users in betting units
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"picks-service/odds"
+)
+
+type OddsStats struct {
+	UserID          int     `json:"user_id"`
+	GradedPicks     int     `json:"graded_picks"`
+	PicksWithOdds   int     `json:"picks_with_odds"`
+	Wins            int     `json:"wins"`
+	UnitsWon        float64 `json:"units_won"`
+	ROI             float64 `json:"roi"`
+	AverageOdds     float64 `json:"average_odds"`
+	PicksWithCLV    int     `json:"picks_with_clv"`
+	AverageCLV      float64 `json:"average_clv"`
+	BeatClosingLine float64 `json:"beat_closing_line_rate"`
+}
+
+// lookupMatchupOdds returns the current odds of a matchup, or nil if the source has none
+func lookupMatchupOdds(source odds.Source, matchup_id string) map[string]int {
+	quote, err := source.GetMatchupOdds(context.Background(), matchup_id)
+	if err != nil {
+		if !errors.Is(err, odds.ErrOddsNotFound) {
+			log.Printf("Error looking up odds for matchup %s: %v", matchup_id, err)
+		}
+		return nil
+	}
+	return quote.Fighters
+}
+
+// lookupPickOdds returns the current odds of the selected fighter, or nil if unknown
+func lookupPickOdds(source odds.Source, matchup_id string, selection_fighter_id string) *int {
+	american, ok := lookupMatchupOdds(source, matchup_id)[selection_fighter_id]
+	if !ok || !odds.Valid(american) {
+		return nil
+	}
+	return &american
+}
+
+// ClosingOddsGrace is how long after a matchup locks its closing odds may still be captured. Past that the
+// quote could be taken mid-fight or after it, so the matchup is left without closing odds instead
+const ClosingOddsGrace = 15 * time.Minute
+
+// CaptureClosingOdds stores the current odds of every ungraded matchup that locked under the store's lock mode in the
+// last ClosingOddsGrace and hasn't been captured yet. Odds are quoted from the store's source before anything is
+// written so a slow source doesn't hold locks. Returns the number of matchups captured
+func (s *PostgresPickStore) CaptureClosingOdds(now time.Time) (int, error) {
+	sqlStatement := `SELECT matchup_id, event_id, card_starts_at, fight_starts_at
+		FROM public.matchup_schedule ms
+		WHERE (card_starts_at BETWEEN $1 AND $2 OR fight_starts_at BETWEEN $1 AND $2)
+			AND NOT EXISTS (SELECT 1 FROM public.matchup_results mr WHERE mr.matchup_id = ms.matchup_id)
+			AND NOT EXISTS (SELECT 1 FROM public.matchup_closing_odds co WHERE co.matchup_id = ms.matchup_id);`
+	rows, err := s.db.Query(sqlStatement, now.Add(-ClosingOddsGrace), now)
+	if err != nil {
+		return 0, fmt.Errorf("error querying locked matchups: %w", err)
+	}
+	defer rows.Close()
+
+	var locked []MatchupSchedule
+	for rows.Next() {
+		var schedule MatchupSchedule
+		var cardStartsAt, fightStartsAt sql.NullTime
+		if err := rows.Scan(&schedule.MatchupID, &schedule.EventID, &cardStartsAt, &fightStartsAt); err != nil {
+			return 0, fmt.Errorf("error scanning schedule row: %w", err)
+		}
+		if cardStartsAt.Valid {
+			schedule.CardStartsAt = &cardStartsAt.Time
+		}
+		if fightStartsAt.Valid {
+			schedule.FightStartsAt = &fightStartsAt.Time
+		}
+
+		//a fight further down the card may start in the window without the matchup having locked yet
+		lockAt, ok := schedule.LockTime(s.lockMode)
+		if !ok || lockAt.After(now) || lockAt.Before(now.Add(-ClosingOddsGrace)) {
+			continue
+		}
+		locked = append(locked, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating schedule rows: %w", err)
+	}
+	rows.Close()
+
+	sqlInsert := `INSERT INTO public.matchup_closing_odds (matchup_id, event_id, fighter_id, american, captured_at)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (matchup_id, fighter_id) DO NOTHING;`
+	captured := 0
+	for _, schedule := range locked {
+		closing := lookupMatchupOdds(s.odds, schedule.MatchupID)
+		stored := false
+		for fighterId, american := range closing {
+			if !odds.Valid(american) {
+				continue
+			}
+			if _, err := s.db.Exec(sqlInsert, schedule.MatchupID, schedule.EventID, fighterId, american, now); err != nil {
+				return captured, fmt.Errorf("unable to store closing odds for matchup %s: %w", schedule.MatchupID, err)
+			}
+			stored = true
+		}
+		if stored {
+			captured++
+		}
+	}
+	return captured, nil
+}
+
+// recordClosingOdds stamps the pending picks of the matchup with the closing odds captured at lock.
+// Picks that were already graded keep whatever closing odds they have
+func recordClosingOdds(tx *sql.Tx, matchup_id string, event_id string) error {
+	sqlStatement := `UPDATE public.picks p SET closing_odds = co.american
+		FROM public.matchup_closing_odds co
+		WHERE co.matchup_id = p.matchup_id AND co.fighter_id = p.selection_fighter_id
+			AND p.matchup_id = $1 AND p.event_id = $2 AND p.closing_odds IS NULL AND p.pick_result = $3;`
+	if _, err := tx.ExecContext(context.Background(), sqlStatement, matchup_id, event_id, PickResultPending); err != nil {
+		return fmt.Errorf("unable to record closing odds for matchup %s: %w", matchup_id, err)
+	}
+	return nil
+}
+
+// GetUserOddsStats scores a user's graded picks as 1 unit bets at the odds they were made at.
+// CLV (closing line value) is the implied probability of the closing odds minus that of the odds
+// taken, so a positive average means the user tends to get better prices than the market closes at
+func GetUserOddsStats(userID int) (OddsStats, error) {
+	stats := OddsStats{UserID: userID}
+
+	sqlStatement := `SELECT pick_result, odds_at_pick, closing_odds FROM public.picks
+		WHERE user_id = $1 AND pick_result IN ($2, $3);`
+	rows, err := usersDb.Query(sqlStatement, userID, PickResultCorrect, PickResultIncorrect)
+	if err != nil {
+		return stats, fmt.Errorf("error querying graded picks for user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	var oddsTotal, clvTotal float64
+	var beatClose int
+	for rows.Next() {
+		var result string
+		var taken, closing sql.NullInt64
+		if err := rows.Scan(&result, &taken, &closing); err != nil {
+			return stats, fmt.Errorf("error scanning graded pick row: %w", err)
+		}
+
+		stats.GradedPicks++
+		if !taken.Valid || !odds.Valid(int(taken.Int64)) {
+			continue
+		}
+
+		american := int(taken.Int64)
+		stats.PicksWithOdds++
+		oddsTotal += float64(american)
+		if result == PickResultCorrect {
+			stats.Wins++
+			stats.UnitsWon += odds.Payout(american)
+		} else {
+			stats.UnitsWon--
+		}
+
+		if closing.Valid && odds.Valid(int(closing.Int64)) {
+			clv := odds.ImpliedProbability(int(closing.Int64)) - odds.ImpliedProbability(american)
+			stats.PicksWithCLV++
+			clvTotal += clv
+			if clv > 0 {
+				beatClose++
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return stats, fmt.Errorf("error reading graded picks: %w", err)
+	}
+
+	if stats.PicksWithOdds > 0 {
+		stats.ROI = stats.UnitsWon / float64(stats.PicksWithOdds)
+		stats.AverageOdds = oddsTotal / float64(stats.PicksWithOdds)
+	}
+	if stats.PicksWithCLV > 0 {
+		stats.AverageCLV = clvTotal / float64(stats.PicksWithCLV)
+		stats.BeatClosingLine = float64(beatClose) / float64(stats.PicksWithCLV)
+	}
+	return stats, nil
+}
@@ -0,0 +1,340 @@
+// usersDBPaginationUtils pages, filters and sorts pick listings with opaque keyset cursors, and pages the
+// other listings (history, survivor picks, leagues, leaderboards) in the same envelope
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrInvalidPickQuery = errors.New("invalid pick query")
+
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+// page sizes of pick listings
+const (
+	DefaultPickPageSize = 50
+	MaxPickPageSize     = 200
+)
+
+// columns pick listings can be sorted by. ties are always broken by pick_id
+const (
+	PickSortID      = "pick_id"
+	PickSortCreated = "created_at"
+	PickSortUpdated = "updated_at"
+)
+
+// PickQuery filters, sorts and pages a pick listing. From and To bound when the pick was made;
+// Cursor is the NextCursor of the previous page, and is only valid with the same sort and order
+type PickQuery struct {
+	Results []string
+	From    *time.Time
+	To      *time.Time
+	Sort    string
+	Desc    bool
+	Limit   int
+	Cursor  string
+}
+
+// Page is one page of a listing. Total counts every item matching the filters, across all pages
+type Page[T any] struct {
+	Data       []T     `json:"data"`
+	NextCursor *string `json:"next_cursor"`
+	Total      int     `json:"total"`
+}
+
+// PickPage is one page of a pick listing
+type PickPage = Page[Pick]
+
+// ListQuery pages a listing with a fixed order. Cursor is the NextCursor of the previous page
+type ListQuery struct {
+	Limit  int
+	Cursor string
+}
+
+// listings paged with a ListQuery, which their cursors are tied to
+const (
+	listingUserHistory    = "user_history"
+	listingMatchupHistory = "matchup_history"
+	listingSurvivorPicks  = "survivor_picks"
+	listingLeagues        = "leagues"
+	listingLeagueMembers  = "league_members"
+	listingLeaderboard    = "leaderboard"
+)
+
+// listCursor is the position in a listing paged by offset. Unlike pick listings these are short, ranked or
+// only ever appended to in the order they're listed in, so an offset stays stable between pages
+type listCursor struct {
+	Listing string `json:"l"`
+	Offset  int    `json:"o"`
+}
+
+// normalize applies the default page size and decodes the cursor into an offset. listing names the
+// endpoint, so a cursor issued by one listing is rejected by another
+func (q ListQuery) normalize(listing string, defaultLimit int, maxLimit int) (limit int, offset int, err error) {
+	limit = q.Limit
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	if limit < 0 || limit > maxLimit {
+		return 0, 0, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListQuery, maxLimit)
+	}
+
+	if q.Cursor == "" {
+		return limit, 0, nil
+	}
+	var cursor listCursor
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err == nil {
+		err = json.Unmarshal(raw, &cursor)
+	}
+	if err != nil || cursor.Offset < 0 {
+		return 0, 0, fmt.Errorf("%w: malformed cursor", ErrInvalidListQuery)
+	}
+	if cursor.Listing != listing {
+		return 0, 0, fmt.Errorf("%w: cursor was issued for a different listing", ErrInvalidListQuery)
+	}
+	return limit, cursor.Offset, nil
+}
+
+// finishListPage wraps one page of a listing fetched at offset, with a cursor if more items follow it
+func finishListPage[T any](listing string, items []T, offset int, total int) Page[T] {
+	page := Page[T]{Data: items, Total: total}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+	if next := offset + len(page.Data); len(page.Data) > 0 && next < total {
+		raw, _ := json.Marshal(listCursor{Listing: listing, Offset: next})
+		cursor := base64.RawURLEncoding.EncodeToString(raw)
+		page.NextCursor = &cursor
+	}
+	return page
+}
+
+// pickCursor is the position after the last pick of a page
+type pickCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v,omitempty"`
+	ID    int    `json:"i"`
+}
+
+// normalize applies defaults, rejects unknown values and decodes the cursor
+func (q PickQuery) normalize() (PickQuery, *pickCursor, error) {
+	if q.Sort == "" {
+		q.Sort = PickSortID
+	}
+	if q.Sort != PickSortID && q.Sort != PickSortCreated && q.Sort != PickSortUpdated {
+		return q, nil, fmt.Errorf("%w: sort must be one of %s, %s or %s", ErrInvalidPickQuery, PickSortID, PickSortCreated, PickSortUpdated)
+	}
+
+	if q.Limit == 0 {
+		q.Limit = DefaultPickPageSize
+	}
+	if q.Limit < 0 || q.Limit > MaxPickPageSize {
+		return q, nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidPickQuery, MaxPickPageSize)
+	}
+
+	for _, result := range q.Results {
+		switch result {
+		case PickResultPending, PickResultCorrect, PickResultIncorrect, PickResultVoid, PickResultPush, PickResultCancelled:
+		default:
+			return q, nil, fmt.Errorf("%w: unknown result status %q", ErrInvalidPickQuery, result)
+		}
+	}
+
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return q, nil, fmt.Errorf("%w: from must be before to", ErrInvalidPickQuery)
+	}
+
+	if q.Cursor == "" {
+		return q, nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return q, nil, fmt.Errorf("%w: malformed cursor", ErrInvalidPickQuery)
+	}
+	var cursor pickCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return q, nil, fmt.Errorf("%w: malformed cursor", ErrInvalidPickQuery)
+	}
+	if cursor.Sort != q.Sort || cursor.Desc != q.Desc {
+		return q, nil, fmt.Errorf("%w: cursor was issued for a different sort", ErrInvalidPickQuery)
+	}
+	return q, &cursor, nil
+}
+
+// sortValue is the value of the sort column a cursor resumes from
+func (q PickQuery) sortValue(p Pick) string {
+	switch q.Sort {
+	case PickSortCreated:
+		return p.CreatedAt
+	case PickSortUpdated:
+		return p.UpdatedAt
+	}
+	return ""
+}
+
+// finishPage trims the one extra pick fetched to detect a next page and encodes the cursor after the last kept pick
+func finishPage(q PickQuery, picks []Pick, total int) PickPage {
+	page := PickPage{Data: picks, Total: total}
+	if page.Data == nil {
+		page.Data = []Pick{}
+	}
+	if len(page.Data) <= q.Limit {
+		return page
+	}
+
+	page.Data = page.Data[:q.Limit]
+	last := page.Data[len(page.Data)-1]
+	raw, _ := json.Marshal(pickCursor{Sort: q.Sort, Desc: q.Desc, Value: q.sortValue(last), ID: last.PickID})
+	next := base64.RawURLEncoding.EncodeToString(raw)
+	page.NextCursor = &next
+	return page
+}
+
+// listPicks runs a paged listing of the picks matching scope, a WHERE condition over scopeArgs
+func (s *PostgresPickStore) listPicks(scope string, scopeArgs []interface{}, q PickQuery) (PickPage, error) {
+	q, cursor, err := q.normalize()
+	if err != nil {
+		return PickPage{}, err
+	}
+
+	where := []string{scope}
+	args := append([]interface{}{}, scopeArgs...)
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(condition, len(args)))
+	}
+
+	if len(q.Results) > 0 {
+		addCondition("pick_result = ANY($%d)", pq.Array(q.Results))
+	}
+	if q.From != nil {
+		addCondition("created_at >= $%d", q.From.UTC())
+	}
+	if q.To != nil {
+		addCondition("created_at < $%d", q.To.UTC())
+	}
+
+	var total int
+	sqlCount := "SELECT COUNT(*) FROM public.picks WHERE " + strings.Join(where, " AND ") + ";"
+	if err := s.db.QueryRow(sqlCount, args...).Scan(&total); err != nil {
+		return PickPage{}, fmt.Errorf("error counting picks: %w", err)
+	}
+
+	direction, comparison := "ASC", ">"
+	if q.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	if cursor != nil {
+		if q.Sort == PickSortID {
+			addCondition("pick_id "+comparison+" $%d", cursor.ID)
+		} else {
+			args = append(args, cursor.Value, cursor.ID)
+			where = append(where, fmt.Sprintf("(%s, pick_id) %s ($%d::timestamp, $%d)", q.Sort, comparison, len(args)-1, len(args)))
+		}
+	}
+
+	order := "pick_id " + direction
+	if q.Sort != PickSortID {
+		order = q.Sort + " " + direction + ", " + order
+	}
+	args = append(args, q.Limit+1)
+	sqlStatement := fmt.Sprintf("SELECT %s FROM public.picks WHERE %s ORDER BY %s LIMIT $%d;",
+		pickColumns, strings.Join(where, " AND "), order, len(args))
+
+	rows, err := s.db.Query(sqlStatement, args...)
+	if err != nil {
+		return PickPage{}, fmt.Errorf("error querying picks: %w", err)
+	}
+	picks, err := scanPicks(rows)
+	if err != nil {
+		return PickPage{}, err
+	}
+	return finishPage(q, picks, total), nil
+}
+
+// pagePicks applies a PickQuery to picks already in memory, the same way listPicks does in SQL
+func pagePicks(picks []Pick, q PickQuery) (PickPage, error) {
+	q, cursor, err := q.normalize()
+	if err != nil {
+		return PickPage{}, err
+	}
+
+	var matched []Pick
+	for _, p := range picks {
+		if len(q.Results) > 0 && !containsString(q.Results, p.PickResult) {
+			continue
+		}
+		if q.From != nil || q.To != nil {
+			created, err := time.Parse(time.RFC3339Nano, p.CreatedAt)
+			if err != nil || (q.From != nil && created.Before(*q.From)) || (q.To != nil && !created.Before(*q.To)) {
+				continue
+			}
+		}
+		matched = append(matched, p)
+	}
+	total := len(matched)
+
+	//before reports whether a sorts ahead of b in ascending order. timestamps are compared as times since
+	//RFC3339 strings with different precision or offsets don't sort as text
+	before := func(aValue string, aID int, bValue string, bID int) bool {
+		if aTime, bTime := parseSortTime(aValue), parseSortTime(bValue); !aTime.Equal(bTime) {
+			return aTime.Before(bTime)
+		}
+		return aID < bID
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if q.Desc {
+			a, b = b, a
+		}
+		return before(q.sortValue(a), a.PickID, q.sortValue(b), b.PickID)
+	})
+
+	if cursor != nil {
+		start := len(matched)
+		for i, p := range matched {
+			after := before(cursor.Value, cursor.ID, q.sortValue(p), p.PickID)
+			if q.Desc {
+				after = before(q.sortValue(p), p.PickID, cursor.Value, cursor.ID)
+			}
+			if after {
+				start = i
+				break
+			}
+		}
+		matched = matched[start:]
+	}
+
+	if len(matched) > q.Limit+1 {
+		matched = matched[:q.Limit+1]
+	}
+	return finishPage(q, matched, total), nil
+}
+
+// parseSortTime reads a created_at / updated_at value, with unparseable and empty values (pick_id sort) first
+func parseSortTime(value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
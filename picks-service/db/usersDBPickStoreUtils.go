@@ -0,0 +1,666 @@
+// usersDBPickStoreUtils defines PickStore, the pick storage the HTTP handlers and grading depend on,
+// with a Postgres implementation for the service and an in-memory one that needs no database
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"picks-service/events"
+	"picks-service/odds"
+)
+
+// PickStore reads, writes, grades and voids moneyline picks, the props that ride on them and survivor picks
+type PickStore interface {
+	GetPicksForUserAndEvent(userID int, eventID string, q PickQuery) (PickPage, error)
+	GetPicksForEvent(eventID string, q PickQuery) (PickPage, error)
+	GetPicksForMatchup(matchupID string, q PickQuery) (PickPage, error)
+	UpsertPick(user_id string, matchup_id string, event_id string, selection_fighter_id string, probability *float64, meta PickMeta) error
+	UpsertPicksBatch(user_id int, event_id string, picks []BatchPick, meta PickMeta) ([]BatchPickResult, error)
+	UpsertConfidencePicks(user_id int, event_id string, picks []ConfidencePick, meta PickMeta) error
+	UpsertPropPick(user_id int, matchup_id string, event_id string, method string, round *int, overrideLock bool) error
+	GetPropPicksForUserAndEvent(userID int, eventID string) ([]PropPick, error)
+	SubmitSurvivorPick(contestID int, userID int, event_id string, matchup_id string, fighter_id string) (SurvivorPick, error)
+	UpdateMatchupPickResults(winning_fighter_id string, event_id string, matchup_id string, result string) (GradingSummary, error)
+	CancelMatchup(event_id string, matchup_id string) (GradingSummary, error)
+	RebookMatchup(event_id string, matchup_id string, new_matchup_id string, withdrawn_fighter_id string) (RebookSummary, error)
+}
+
+// PostgresPickStore keeps picks in the users database, along with their history, counters and live notifications.
+// Lock checks, card validation and odds quotes all go through the store's own lock mode and sources
+type PostgresPickStore struct {
+	db       *sql.DB
+	lockMode LockMode
+	events   events.Source
+	odds     odds.Source
+}
+
+// NewPostgresPickStore builds a store on db. A nil eventSource skips card validation and a nil oddsSource
+// stores picks without odds
+func NewPostgresPickStore(db *sql.DB, lockMode LockMode, eventSource events.Source, oddsSource odds.Source) *PostgresPickStore {
+	if oddsSource == nil {
+		oddsSource = odds.NoSource{}
+	}
+	return &PostgresPickStore{db: db, lockMode: lockMode, events: eventSource, odds: oddsSource}
+}
+
+// MemoryPickStore keeps picks, props and survivor picks in memory. It follows the same lock, validation, switching,
+// grading and rebooking rules as PostgresPickStore for all three, without odds, history, counters, survivor contests
+// or notifications. Survivor entries only track whether they are alive, not the event that eliminated them
+type MemoryPickStore struct {
+	mu              sync.Mutex
+	picks           []*Pick
+	props           []*PropPick
+	survivorPicks   []*SurvivorPick
+	survivorEntries map[survivorEntry]string
+	nextID          int
+	nextPropID      int
+	schedules       map[string]MatchupSchedule
+	graded          map[string]bool
+	// Now is the clock used for pick locks
+	Now func() time.Time
+	// LockMode decides which start time locks a matchup
+	LockMode LockMode
+	// Events validates picks against event cards when set, e.g. with an events.JSONFileSource fixture
+	Events events.Source
+}
+
+// survivorEntry keys a user's entry in a survivor contest
+type survivorEntry struct {
+	contestID int
+	userID    int
+}
+
+func NewMemoryPickStore() *MemoryPickStore {
+	return &MemoryPickStore{
+		survivorEntries: make(map[survivorEntry]string),
+		nextID:          1,
+		nextPropID:      1,
+		schedules:       make(map[string]MatchupSchedule),
+		graded:          make(map[string]bool),
+		Now:             time.Now,
+		LockMode:        LockAtCardStart,
+	}
+}
+
+// SetSchedule stores the start times that lock a matchup's picks
+func (s *MemoryPickStore) SetSchedule(schedule MatchupSchedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedules[schedule.MatchupID] = schedule
+}
+
+// EnterSurvivor enters a user in a survivor contest. Contests themselves aren't kept in memory
+func (s *MemoryPickStore) EnterSurvivor(contestID int, userID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.survivorEntries[survivorEntry{contestID, userID}] = SurvivorAlive
+}
+
+func (s *MemoryPickStore) GetPicksForUserAndEvent(userID int, eventID string, q PickQuery) (PickPage, error) {
+	return pagePicks(s.filter(func(p *Pick) bool { return p.UserID == userID && p.EventID == eventID }), q)
+}
+
+func (s *MemoryPickStore) GetPicksForEvent(eventID string, q PickQuery) (PickPage, error) {
+	return pagePicks(s.filter(func(p *Pick) bool { return p.EventID == eventID }), q)
+}
+
+func (s *MemoryPickStore) GetPicksForMatchup(matchupID string, q PickQuery) (PickPage, error) {
+	return pagePicks(s.filter(func(p *Pick) bool { return p.MatchupID == matchupID }), q)
+}
+
+// filter returns copies of the matching picks in the order they were made
+func (s *MemoryPickStore) filter(match func(*Pick) bool) []Pick {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var picks []Pick
+	for _, p := range s.picks {
+		if match(p) {
+			pick := *p
+			if p.Probability != nil {
+				probability := *p.Probability
+				pick.Probability = &probability
+			}
+			if p.Confidence != nil {
+				confidence := *p.Confidence
+				pick.Confidence = &confidence
+			}
+			picks = append(picks, pick)
+		}
+	}
+	sort.Slice(picks, func(i, j int) bool { return picks[i].PickID < picks[j].PickID })
+	return picks
+}
+
+// checkLock is checkPickLock against the in-memory results and schedules. The caller holds s.mu
+func (s *MemoryPickStore) checkLock(matchup_id string) error {
+	var schedule *MatchupSchedule
+	if stored, ok := s.schedules[matchup_id]; ok {
+		schedule = &stored
+	}
+	return pickLockError(s.graded[matchup_id], schedule, s.LockMode, s.Now())
+}
+
+// scheduled returns the matchups scheduled for an event, like getEventMatchupIds. The caller holds s.mu
+func (s *MemoryPickStore) scheduled(event_id string) []string {
+	var ids []string
+	for id, schedule := range s.schedules {
+		if schedule.EventID == event_id {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// findPick returns the user's pick on a matchup, or nil. The caller holds s.mu
+func (s *MemoryPickStore) findPick(user_id int, matchup_id string, event_id string) *Pick {
+	for _, p := range s.picks {
+		if p.UserID == user_id && p.MatchupID == matchup_id && p.EventID == event_id {
+			return p
+		}
+	}
+	return nil
+}
+
+// writePick mirrors PostgresPickStore.writePick without the lock check or history. The caller holds s.mu
+func (s *MemoryPickStore) writePick(user_id int, matchup_id string, event_id string, selection_fighter_id string) (inserted bool) {
+	now := s.Now().UTC().Format(time.RFC3339)
+	if p := s.findPick(user_id, matchup_id, event_id); p != nil {
+		if p.PickResult == PickResultCancelled {
+			p.PickResult = PickResultPending
+		}
+		if p.SelectionFighterID != selection_fighter_id {
+			p.Probability = nil
+		}
+		p.SelectionFighterID = selection_fighter_id
+		p.UpdatedAt = now
+		return false
+	}
+
+	s.picks = append(s.picks, &Pick{
+		PickID:             s.nextID,
+		UserID:             user_id,
+		MatchupID:          matchup_id,
+		EventID:            event_id,
+		SelectionFighterID: selection_fighter_id,
+		PickResult:         PickResultPending,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	})
+	s.nextID++
+	return true
+}
+
+func (s *MemoryPickStore) UpsertPick(user_id string, matchup_id string, event_id string, selection_fighter_id string, probability *float64, meta PickMeta) error {
+	if err := ValidatePickProbability(probability); err != nil {
+		return err
+	}
+	if err := validatePickWith(s.Events, event_id, matchup_id, selection_fighter_id); err != nil {
+		return err
+	}
+	userId, err := strconv.Atoi(user_id)
+	if err != nil {
+		return fmt.Errorf("invalid user id %q: %w", user_id, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !meta.OverrideLock {
+		if err := s.checkLock(matchup_id); err != nil {
+			return err
+		}
+	}
+
+	s.writePick(userId, matchup_id, event_id, selection_fighter_id)
+	var copied *float64
+	if probability != nil {
+		value := *probability
+		copied = &value
+	}
+	s.findPick(userId, matchup_id, event_id).Probability = copied
+	return nil
+}
+
+func (s *MemoryPickStore) UpsertPicksBatch(user_id int, event_id string, picks []BatchPick, meta PickMeta) ([]BatchPickResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results, err := checkBatch(event_id, picks, s.scheduled(event_id), s.Events, s.checkLock)
+	if err != nil {
+		return results, err
+	}
+	for i, p := range picks {
+		if s.writePick(user_id, p.MatchupID, event_id, p.SelectionFighterID) {
+			results[i].Status = BatchStatusInserted
+		} else {
+			results[i].Status = BatchStatusUpdated
+		}
+	}
+	return results, nil
+}
+
+func (s *MemoryPickStore) UpsertConfidencePicks(user_id int, event_id string, picks []ConfidencePick, meta PickMeta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkConfidencePicks(event_id, picks, s.scheduled(event_id), s.Events, s.checkLock); err != nil {
+		return err
+	}
+
+	for _, p := range s.picks {
+		if p.UserID == user_id && p.EventID == event_id {
+			p.Confidence = nil
+		}
+	}
+	for _, p := range picks {
+		s.writePick(user_id, p.MatchupID, event_id, p.SelectionFighterID)
+		confidence := p.Confidence
+		s.findPick(user_id, p.MatchupID, event_id).Confidence = &confidence
+	}
+	return nil
+}
+
+func (s *MemoryPickStore) UpsertPropPick(user_id int, matchup_id string, event_id string, method string, round *int, overrideLock bool) error {
+	if err := validateMatchupWith(s.Events, event_id, matchup_id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !overrideLock {
+		if err := s.checkLock(matchup_id); err != nil {
+			return err
+		}
+	}
+
+	scheduledRounds := MaxScheduledRounds
+	if schedule, ok := s.schedules[matchup_id]; ok && schedule.ScheduledRounds > 0 {
+		scheduledRounds = schedule.ScheduledRounds
+	}
+	if err := ValidatePropPick(method, round, scheduledRounds); err != nil {
+		return err
+	}
+	method, _ = NormalizeMethod(method)
+
+	var copied *int
+	var roundResult *string
+	if round != nil {
+		value := *round
+		copied = &value
+		pending := PickResultPending
+		roundResult = &pending
+	}
+	now := s.Now().UTC().Format(time.RFC3339)
+
+	for _, prop := range s.props {
+		if prop.UserID == user_id && prop.MatchupID == matchup_id && prop.EventID == event_id {
+			prop.Method = method
+			prop.Round = copied
+			prop.MethodResult = PickResultPending
+			prop.RoundResult = roundResult
+			prop.UpdatedAt = now
+			return nil
+		}
+	}
+
+	s.props = append(s.props, &PropPick{
+		PropPickID:   s.nextPropID,
+		UserID:       user_id,
+		MatchupID:    matchup_id,
+		EventID:      event_id,
+		Method:       method,
+		Round:        copied,
+		MethodResult: PickResultPending,
+		RoundResult:  roundResult,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+	s.nextPropID++
+	return nil
+}
+
+func (s *MemoryPickStore) GetPropPicksForUserAndEvent(userID int, eventID string) ([]PropPick, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var props []PropPick
+	for _, p := range s.props {
+		if p.UserID != userID || p.EventID != eventID {
+			continue
+		}
+		prop := *p
+		if p.Round != nil {
+			round := *p.Round
+			prop.Round = &round
+		}
+		if p.RoundResult != nil {
+			roundResult := *p.RoundResult
+			prop.RoundResult = &roundResult
+		}
+		props = append(props, prop)
+	}
+	return props, nil
+}
+
+func (s *MemoryPickStore) SubmitSurvivorPick(contestID int, userID int, event_id string, matchup_id string, fighter_id string) (SurvivorPick, error) {
+	pick := SurvivorPick{ContestID: contestID, UserID: userID, EventID: event_id, MatchupID: matchup_id, FighterID: fighter_id, Result: PickResultPending}
+	if event_id == "" || matchup_id == "" || fighter_id == "" {
+		return pick, fmt.Errorf("%w: eventId, matchupId and fighterId are required", ErrInvalidSurvivorArg)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	onCard := false
+	for _, id := range s.scheduled(event_id) {
+		onCard = onCard || id == matchup_id
+	}
+	if !onCard {
+		return pick, fmt.Errorf("%w: matchup %s is not on the card for event %s", ErrInvalidSurvivorArg, matchup_id, event_id)
+	}
+	if err := validatePickWith(s.Events, event_id, matchup_id, fighter_id); err != nil {
+		return pick, err
+	}
+	if err := s.checkLock(matchup_id); err != nil {
+		return pick, err
+	}
+
+	status, entered := s.survivorEntries[survivorEntry{contestID, userID}]
+	if !entered {
+		return pick, ErrSurvivorNotEntered
+	}
+	if status != SurvivorAlive {
+		return pick, ErrSurvivorEliminated
+	}
+
+	var current *SurvivorPick
+	used := false
+	for _, p := range s.survivorPicks {
+		if p.ContestID != contestID || p.UserID != userID {
+			continue
+		}
+		if p.EventID == event_id {
+			current = p
+		} else if p.FighterID == fighter_id && p.Result != PickResultCancelled {
+			used = true
+		}
+	}
+	if current != nil && current.Result != PickResultCancelled && current.MatchupID != matchup_id {
+		if err := s.checkLock(current.MatchupID); err != nil {
+			return pick, ErrSurvivorPickLocked
+		}
+	}
+	if used {
+		return pick, ErrSurvivorFighterUsed
+	}
+
+	pick.UpdatedAt = s.Now().UTC().Format(time.RFC3339)
+	if current == nil {
+		current = &SurvivorPick{}
+		s.survivorPicks = append(s.survivorPicks, current)
+	}
+	*current = pick
+	return pick, nil
+}
+
+func (s *MemoryPickStore) UpdateMatchupPickResults(winning_fighter_id string, event_id string, matchup_id string, result string) (GradingSummary, error) {
+	summary := GradingSummary{MatchupID: matchup_id, EventID: event_id, WinnerFighterID: winning_fighter_id}
+
+	//a result that can't be parsed still grades the moneyline, props just get voided
+	fightResult, _ := ParseFightResult(result)
+	noWinnerResult := PickResultVoid
+	if winning_fighter_id == "" {
+		fightResult = FightResult{}
+		if NoWinnerOutcome(result) == OutcomeDraw {
+			noWinnerResult = PickResultPush
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.graded[matchup_id] = true
+	s.gradePicks(matchup_id, event_id, winning_fighter_id, noWinnerResult, &summary)
+	s.gradeSurvivorPicks(matchup_id, event_id, winning_fighter_id, noWinnerResult)
+	s.gradeProps(matchup_id, event_id, fightResult)
+	return summary, nil
+}
+
+// gradePicks mirrors gradePicks on the in-memory picks. The caller holds s.mu
+func (s *MemoryPickStore) gradePicks(matchup_id string, event_id string, winning_fighter_id string, noWinnerResult string, summary *GradingSummary) {
+	for _, p := range s.picks {
+		if p.MatchupID != matchup_id || p.EventID != event_id || p.PickResult == PickResultCancelled {
+			continue
+		}
+
+		next := noWinnerResult
+		if winning_fighter_id != "" {
+			next = PickResultIncorrect
+			if p.SelectionFighterID == winning_fighter_id {
+				next = PickResultCorrect
+			}
+		}
+		if p.PickResult == next {
+			continue
+		}
+
+		if p.PickResult != PickResultPending {
+			summary.Regraded++
+		}
+		switch next {
+		case PickResultCorrect:
+			summary.Correct++
+		case PickResultIncorrect:
+			summary.Incorrect++
+		case PickResultVoid:
+			summary.Void++
+		case PickResultPush:
+			summary.Push++
+		case PickResultCancelled:
+			summary.Cancelled++
+		}
+		p.PickResult = next
+		p.UpdatedAt = s.Now().UTC().Format(time.RFC3339)
+	}
+}
+
+// gradeSurvivorPicks mirrors gradeSurvivorPicks, recomputing the status of every entry the matchup touched.
+// The caller holds s.mu
+func (s *MemoryPickStore) gradeSurvivorPicks(matchup_id string, event_id string, winning_fighter_id string, noWinnerResult string) {
+	touched := make(map[survivorEntry]bool)
+	for _, p := range s.survivorPicks {
+		if p.MatchupID != matchup_id || p.EventID != event_id {
+			continue
+		}
+		touched[survivorEntry{p.ContestID, p.UserID}] = true
+		if p.Result == PickResultCancelled {
+			continue
+		}
+
+		p.Result = noWinnerResult
+		if winning_fighter_id != "" {
+			p.Result = PickResultIncorrect
+			if p.FighterID == winning_fighter_id {
+				p.Result = PickResultCorrect
+			}
+		}
+		p.UpdatedAt = s.Now().UTC().Format(time.RFC3339)
+	}
+
+	for entry := range touched {
+		status := SurvivorAlive
+		for _, p := range s.survivorPicks {
+			if p.ContestID == entry.contestID && p.UserID == entry.userID && p.Result == PickResultIncorrect {
+				status = SurvivorEliminated
+			}
+		}
+		s.survivorEntries[entry] = status
+	}
+}
+
+// gradeProps mirrors gradePropPicks: a prop only counts when the moneyline pick it rides on was correct, and round
+// props are voided when the result didn't say which round the finish came in. The caller holds s.mu
+func (s *MemoryPickStore) gradeProps(matchup_id string, event_id string, result FightResult) {
+	for _, prop := range s.props {
+		if prop.MatchupID != matchup_id || prop.EventID != event_id || prop.MethodResult == PickResultCancelled {
+			continue
+		}
+
+		//props without a moneyline pick have nothing to ride on
+		p := s.findPick(prop.UserID, matchup_id, event_id)
+		won := p != nil && p.PickResult == PickResultCorrect
+
+		switch {
+		case p == nil || result.Method == "":
+			prop.MethodResult = PickResultVoid
+		case won && prop.Method == result.Method:
+			prop.MethodResult = PickResultCorrect
+		default:
+			prop.MethodResult = PickResultIncorrect
+		}
+
+		if prop.Round == nil {
+			prop.RoundResult = nil
+			continue
+		}
+		roundResult := PickResultIncorrect
+		switch {
+		case p == nil || result.Method == "" || (result.Method != MethodDecision && result.Round == 0):
+			roundResult = PickResultVoid
+		case won && result.Method != MethodDecision && *prop.Round == result.Round:
+			roundResult = PickResultCorrect
+		}
+		prop.RoundResult = &roundResult
+	}
+}
+
+// cancelProps cancels the props of a matchup that match. The caller holds s.mu
+func (s *MemoryPickStore) cancelProps(matchup_id string, event_id string, match func(*PropPick) bool) {
+	for _, prop := range s.props {
+		if prop.MatchupID != matchup_id || prop.EventID != event_id || !match(prop) {
+			continue
+		}
+		prop.MethodResult = PickResultCancelled
+		if prop.Round != nil {
+			cancelled := PickResultCancelled
+			prop.RoundResult = &cancelled
+		}
+	}
+}
+
+func (s *MemoryPickStore) CancelMatchup(event_id string, matchup_id string) (GradingSummary, error) {
+	summary := GradingSummary{MatchupID: matchup_id, EventID: event_id}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.graded[matchup_id] = true
+	s.gradePicks(matchup_id, event_id, "", PickResultCancelled, &summary)
+	s.gradeSurvivorPicks(matchup_id, event_id, "", PickResultCancelled)
+	s.cancelProps(matchup_id, event_id, func(*PropPick) bool { return true })
+	return summary, nil
+}
+
+func (s *MemoryPickStore) RebookMatchup(event_id string, matchup_id string, new_matchup_id string, withdrawn_fighter_id string) (RebookSummary, error) {
+	if new_matchup_id == "" {
+		new_matchup_id = matchup_id
+	}
+	summary := RebookSummary{MatchupID: matchup_id, NewMatchupID: new_matchup_id, EventID: event_id, WithdrawnFighterID: withdrawn_fighter_id}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.graded[matchup_id] {
+		return summary, ErrMatchupGraded
+	}
+	now := s.Now().UTC().Format(time.RFC3339)
+
+	//props first, while the moneyline picks they ride on are still pending on the old matchup
+	s.cancelProps(matchup_id, event_id, func(prop *PropPick) bool {
+		p := s.findPick(prop.UserID, matchup_id, event_id)
+		return p != nil && p.SelectionFighterID == withdrawn_fighter_id && p.PickResult == PickResultPending
+	})
+	for _, p := range s.picks {
+		if p.MatchupID == matchup_id && p.EventID == event_id && p.SelectionFighterID == withdrawn_fighter_id && p.PickResult == PickResultPending {
+			p.PickResult = PickResultCancelled
+			p.UpdatedAt = now
+			summary.Cancelled++
+		}
+	}
+
+	//like rebookSurvivorPicks, a survivor pick on the withdrawn fighter frees the fighter and the rest move along
+	for _, p := range s.survivorPicks {
+		if p.MatchupID != matchup_id || p.EventID != event_id || p.Result != PickResultPending {
+			continue
+		}
+		switch {
+		case p.FighterID == withdrawn_fighter_id:
+			p.Result = PickResultCancelled
+		case new_matchup_id != matchup_id:
+			p.MatchupID = new_matchup_id
+		default:
+			continue
+		}
+		p.UpdatedAt = now
+	}
+	if new_matchup_id == matchup_id {
+		return summary, nil
+	}
+
+	for _, prop := range s.props {
+		if prop.MatchupID != matchup_id || prop.EventID != event_id {
+			continue
+		}
+		p := s.findPick(prop.UserID, matchup_id, event_id)
+		if p == nil || p.PickResult != PickResultPending || s.findPick(prop.UserID, new_matchup_id, event_id) != nil {
+			continue
+		}
+		taken := false
+		for _, other := range s.props {
+			taken = taken || (other.UserID == prop.UserID && other.MatchupID == new_matchup_id && other.EventID == event_id)
+		}
+		if !taken {
+			prop.MatchupID = new_matchup_id
+		}
+	}
+
+	//a user who already picked the new matchup keeps that pick and loses the old one
+	for _, p := range s.picks {
+		if p.MatchupID != matchup_id || p.EventID != event_id || p.PickResult != PickResultPending {
+			continue
+		}
+		if s.findPick(p.UserID, new_matchup_id, event_id) == nil {
+			p.MatchupID = new_matchup_id
+			p.OddsAtPick = nil
+			summary.Migrated++
+		} else {
+			p.PickResult = PickResultCancelled
+			summary.Cancelled++
+		}
+		p.UpdatedAt = now
+	}
+	s.cancelProps(matchup_id, event_id, func(prop *PropPick) bool { return prop.MethodResult == PickResultPending })
+
+	if schedule, ok := s.schedules[matchup_id]; ok && schedule.EventID == event_id {
+		if _, exists := s.schedules[new_matchup_id]; !exists {
+			schedule.MatchupID = new_matchup_id
+			if schedule.Fighter1ID == withdrawn_fighter_id {
+				schedule.Fighter1ID, schedule.Fighter1Name = "", ""
+			}
+			if schedule.Fighter2ID == withdrawn_fighter_id {
+				schedule.Fighter2ID, schedule.Fighter2Name = "", ""
+			}
+			s.schedules[new_matchup_id] = schedule
+		}
+		delete(s.schedules, matchup_id)
+	}
+	return summary, nil
+}
@@ -0,0 +1,433 @@
+package db
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 4, 13, 20, 0, 0, 0, time.UTC)
+
+// newTestStore returns a store at testNow with m1 and m2 on e1's card, starting in an hour
+func newTestStore(t *testing.T) *MemoryPickStore {
+	t.Helper()
+	store := NewMemoryPickStore()
+	store.Now = func() time.Time { return testNow }
+
+	start := testNow.Add(time.Hour)
+	for _, id := range []string{"m1", "m2"} {
+		store.SetSchedule(MatchupSchedule{MatchupID: id, EventID: "e1", CardType: CardTypeMainCard, CardStartsAt: &start,
+			Fighter1ID: "f1", Fighter2ID: "f2", ScheduledRounds: 3})
+	}
+	return store
+}
+
+func mustPick(t *testing.T, store *MemoryPickStore, user_id int, matchup_id string, selection string) {
+	t.Helper()
+	if err := store.UpsertPick(strconv.Itoa(user_id), matchup_id, "e1", selection, nil, PickMeta{}); err != nil {
+		t.Fatalf("UpsertPick(%d, %s, %s): %v", user_id, matchup_id, selection, err)
+	}
+}
+
+func mustProp(t *testing.T, store *MemoryPickStore, user_id int, matchup_id string, method string, round *int) {
+	t.Helper()
+	if err := store.UpsertPropPick(user_id, matchup_id, "e1", method, round, false); err != nil {
+		t.Fatalf("UpsertPropPick(%d, %s, %s): %v", user_id, matchup_id, method, err)
+	}
+}
+
+func pickResult(t *testing.T, store *MemoryPickStore, user_id int, matchup_id string) string {
+	t.Helper()
+	page, err := store.GetPicksForUserAndEvent(user_id, "e1", PickQuery{})
+	if err != nil {
+		t.Fatalf("GetPicksForUserAndEvent(%d): %v", user_id, err)
+	}
+	for _, p := range page.Data {
+		if p.MatchupID == matchup_id {
+			return p.PickResult
+		}
+	}
+	return ""
+}
+
+// propResults returns the method and round results of the user's prop on m1; round is empty without a round prop
+func propResults(t *testing.T, store *MemoryPickStore, user_id int) (string, string) {
+	t.Helper()
+	props, err := store.GetPropPicksForUserAndEvent(user_id, "e1")
+	if err != nil {
+		t.Fatalf("GetPropPicksForUserAndEvent(%d): %v", user_id, err)
+	}
+	for _, prop := range props {
+		if prop.MatchupID != "m1" {
+			continue
+		}
+		if prop.RoundResult == nil {
+			return prop.MethodResult, ""
+		}
+		return prop.MethodResult, *prop.RoundResult
+	}
+	t.Fatalf("no prop for user %d", user_id)
+	return "", ""
+}
+
+func TestMemoryPickStoreGrading(t *testing.T) {
+	tests := []struct {
+		name    string
+		winner  string
+		result  string
+		want    map[int]string
+		summary GradingSummary
+		// method and round results of user 1 (KO/TKO in round 1), user 2 (SUB) and user 3 (KO/TKO, no moneyline)
+		wantProps map[int][2]string
+	}{
+		{
+			name:    "finish in the picked round",
+			winner:  "f1",
+			result:  "FinalKO/TKOR1, 0:21",
+			want:    map[int]string{1: PickResultCorrect, 2: PickResultIncorrect},
+			summary: GradingSummary{Correct: 1, Incorrect: 1},
+			wantProps: map[int][2]string{
+				1: {PickResultCorrect, PickResultCorrect},
+				2: {PickResultIncorrect, ""},
+				3: {PickResultVoid, ""},
+			},
+		},
+		{
+			name:    "finish in another round",
+			winner:  "f1",
+			result:  "FinalKO/TKOR2, 4:59",
+			want:    map[int]string{1: PickResultCorrect, 2: PickResultIncorrect},
+			summary: GradingSummary{Correct: 1, Incorrect: 1},
+			wantProps: map[int][2]string{
+				1: {PickResultCorrect, PickResultIncorrect},
+				2: {PickResultIncorrect, ""},
+				3: {PickResultVoid, ""},
+			},
+		},
+		{
+			name:    "decision",
+			winner:  "f1",
+			result:  "FinalU DecR3, 5:00",
+			want:    map[int]string{1: PickResultCorrect, 2: PickResultIncorrect},
+			summary: GradingSummary{Correct: 1, Incorrect: 1},
+			wantProps: map[int][2]string{
+				1: {PickResultIncorrect, PickResultIncorrect},
+				2: {PickResultIncorrect, ""},
+				3: {PickResultVoid, ""},
+			},
+		},
+		{
+			name:    "props only count with the moneyline",
+			winner:  "f2",
+			result:  "FinalSubR2, 3:10",
+			want:    map[int]string{1: PickResultIncorrect, 2: PickResultCorrect},
+			summary: GradingSummary{Correct: 1, Incorrect: 1},
+			wantProps: map[int][2]string{
+				1: {PickResultIncorrect, PickResultIncorrect},
+				2: {PickResultCorrect, ""},
+				3: {PickResultVoid, ""},
+			},
+		},
+		{
+			name:    "unparseable result voids props",
+			winner:  "f1",
+			result:  "???",
+			want:    map[int]string{1: PickResultCorrect, 2: PickResultIncorrect},
+			summary: GradingSummary{Correct: 1, Incorrect: 1},
+			wantProps: map[int][2]string{
+				1: {PickResultVoid, PickResultVoid},
+				2: {PickResultVoid, ""},
+				3: {PickResultVoid, ""},
+			},
+		},
+		{
+			name:    "draw pushes",
+			result:  "FinalDrawR3, 5:00",
+			want:    map[int]string{1: PickResultPush, 2: PickResultPush},
+			summary: GradingSummary{Push: 2},
+			wantProps: map[int][2]string{
+				1: {PickResultVoid, PickResultVoid},
+				2: {PickResultVoid, ""},
+				3: {PickResultVoid, ""},
+			},
+		},
+		{
+			name:    "no contest voids",
+			result:  "FinalNCR1, 2:11",
+			want:    map[int]string{1: PickResultVoid, 2: PickResultVoid},
+			summary: GradingSummary{Void: 2},
+			wantProps: map[int][2]string{
+				1: {PickResultVoid, PickResultVoid},
+				2: {PickResultVoid, ""},
+				3: {PickResultVoid, ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			round := 1
+			mustPick(t, store, 1, "m1", "f1")
+			mustPick(t, store, 2, "m1", "f2")
+			mustProp(t, store, 1, "m1", "ko", &round)
+			mustProp(t, store, 2, "m1", "submission", nil)
+			mustProp(t, store, 3, "m1", "KO/TKO", nil)
+
+			summary, err := store.UpdateMatchupPickResults(tt.winner, "e1", "m1", tt.result)
+			if err != nil {
+				t.Fatalf("UpdateMatchupPickResults: %v", err)
+			}
+			tt.summary.MatchupID, tt.summary.EventID, tt.summary.WinnerFighterID = "m1", "e1", tt.winner
+			if summary != tt.summary {
+				t.Errorf("summary = %+v, want %+v", summary, tt.summary)
+			}
+
+			for user, want := range tt.want {
+				if got := pickResult(t, store, user, "m1"); got != want {
+					t.Errorf("user %d pick = %q, want %q", user, got, want)
+				}
+			}
+			for user, want := range tt.wantProps {
+				method, round := propResults(t, store, user)
+				if method != want[0] || round != want[1] {
+					t.Errorf("user %d prop = %q/%q, want %q/%q", user, method, round, want[0], want[1])
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryPickStoreRegrade(t *testing.T) {
+	store := newTestStore(t)
+	mustPick(t, store, 1, "m1", "f1")
+	mustPick(t, store, 2, "m1", "f2")
+
+	if _, err := store.UpdateMatchupPickResults("f1", "e1", "m1", ""); err != nil {
+		t.Fatalf("UpdateMatchupPickResults: %v", err)
+	}
+	summary, err := store.UpdateMatchupPickResults("f2", "e1", "m1", "")
+	if err != nil {
+		t.Fatalf("UpdateMatchupPickResults: %v", err)
+	}
+	want := GradingSummary{MatchupID: "m1", EventID: "e1", WinnerFighterID: "f2", Correct: 1, Incorrect: 1, Regraded: 2}
+	if summary != want {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+
+	//the same result again changes nothing
+	summary, err = store.UpdateMatchupPickResults("f2", "e1", "m1", "")
+	if err != nil {
+		t.Fatalf("UpdateMatchupPickResults: %v", err)
+	}
+	if want := (GradingSummary{MatchupID: "m1", EventID: "e1", WinnerFighterID: "f2"}); summary != want {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+}
+
+func TestMemoryPickStoreCancelAndRebook(t *testing.T) {
+	tests := []struct {
+		name     string
+		apply    func(store *MemoryPickStore) error
+		matchups map[int]string
+		want     map[int]string
+		props    map[int]string
+	}{
+		{
+			name: "cancel",
+			apply: func(store *MemoryPickStore) error {
+				_, err := store.CancelMatchup("e1", "m1")
+				return err
+			},
+			matchups: map[int]string{1: "m1", 2: "m1"},
+			want:     map[int]string{1: PickResultCancelled, 2: PickResultCancelled},
+			props:    map[int]string{1: PickResultCancelled, 2: PickResultCancelled},
+		},
+		{
+			name: "rebook keeping the id",
+			apply: func(store *MemoryPickStore) error {
+				_, err := store.RebookMatchup("e1", "m1", "", "f2")
+				return err
+			},
+			matchups: map[int]string{1: "m1", 2: "m1"},
+			want:     map[int]string{1: PickResultPending, 2: PickResultCancelled},
+			props:    map[int]string{1: PickResultPending, 2: PickResultCancelled},
+		},
+		{
+			name: "rebook with a new id",
+			apply: func(store *MemoryPickStore) error {
+				_, err := store.RebookMatchup("e1", "m1", "m3", "f2")
+				return err
+			},
+			matchups: map[int]string{1: "m3", 2: "m1"},
+			want:     map[int]string{1: PickResultPending, 2: PickResultCancelled},
+			props:    map[int]string{1: PickResultPending, 2: PickResultCancelled},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			mustPick(t, store, 1, "m1", "f1")
+			mustPick(t, store, 2, "m1", "f2")
+			mustProp(t, store, 1, "m1", "DEC", nil)
+			mustProp(t, store, 2, "m1", "DEC", nil)
+
+			if err := tt.apply(store); err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			for user, want := range tt.want {
+				if got := pickResult(t, store, user, tt.matchups[user]); got != want {
+					t.Errorf("user %d pick on %s = %q, want %q", user, tt.matchups[user], got, want)
+				}
+				props, _ := store.GetPropPicksForUserAndEvent(user, "e1")
+				if len(props) != 1 || props[0].MatchupID != tt.matchups[user] || props[0].MethodResult != tt.props[user] {
+					t.Errorf("user %d props = %+v, want %q on %s", user, props, tt.props[user], tt.matchups[user])
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryPickStoreRepickAfterRebook(t *testing.T) {
+	store := newTestStore(t)
+	mustPick(t, store, 1, "m1", "f2")
+	mustProp(t, store, 1, "m1", "SUB", nil)
+	if _, err := store.RebookMatchup("e1", "m1", "", "f2"); err != nil {
+		t.Fatalf("RebookMatchup: %v", err)
+	}
+
+	//the user picks the replacement bout again, which revives the cancelled pick and prop
+	mustPick(t, store, 1, "m1", "f1")
+	mustProp(t, store, 1, "m1", "SUB", nil)
+	if _, err := store.UpdateMatchupPickResults("f1", "e1", "m1", "FinalSubR2, 3:10"); err != nil {
+		t.Fatalf("UpdateMatchupPickResults: %v", err)
+	}
+
+	if got := pickResult(t, store, 1, "m1"); got != PickResultCorrect {
+		t.Errorf("pick = %q, want %q", got, PickResultCorrect)
+	}
+	if method, _ := propResults(t, store, 1); method != PickResultCorrect {
+		t.Errorf("prop = %q, want %q", method, PickResultCorrect)
+	}
+}
+
+func TestMemoryPickStoreRebookGraded(t *testing.T) {
+	store := newTestStore(t)
+	if _, err := store.UpdateMatchupPickResults("f1", "e1", "m1", ""); err != nil {
+		t.Fatalf("UpdateMatchupPickResults: %v", err)
+	}
+	if _, err := store.RebookMatchup("e1", "m1", "m3", "f2"); !errors.Is(err, ErrMatchupGraded) {
+		t.Errorf("RebookMatchup error = %v, want ErrMatchupGraded", err)
+	}
+}
+
+func TestMemoryPickStoreSurvivor(t *testing.T) {
+	tests := []struct {
+		name    string
+		submit  [][3]string // event, matchup, fighter submitted by user 1 in order
+		winner  string
+		wantErr error
+		status  string
+	}{
+		{name: "winner survives", submit: [][3]string{{"e1", "m1", "f1"}}, winner: "f1", status: SurvivorAlive},
+		{name: "loser is eliminated", submit: [][3]string{{"e1", "m1", "f2"}}, winner: "f1", status: SurvivorEliminated},
+		{name: "draw lets everyone through", submit: [][3]string{{"e1", "m1", "f2"}}, status: SurvivorAlive},
+		{name: "switching fights before lock", submit: [][3]string{{"e1", "m2", "f2"}, {"e1", "m1", "f1"}}, winner: "f1", status: SurvivorAlive},
+		{name: "matchup not on the card", submit: [][3]string{{"e1", "m9", "f1"}}, wantErr: ErrInvalidSurvivorArg},
+		{name: "missing fighter", submit: [][3]string{{"e1", "m1", ""}}, wantErr: ErrInvalidSurvivorArg},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			store.EnterSurvivor(1, 1)
+
+			var err error
+			for _, s := range tt.submit {
+				if _, err = store.SubmitSurvivorPick(1, 1, s[0], s[1], s[2]); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SubmitSurvivorPick error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			//without a winner the bout is graded as a draw
+			result := ""
+			if tt.winner == "" {
+				result = "FinalDrawR3, 5:00"
+			}
+			if _, err := store.UpdateMatchupPickResults(tt.winner, "e1", "m1", result); err != nil {
+				t.Fatalf("UpdateMatchupPickResults: %v", err)
+			}
+			if got := store.survivorEntries[survivorEntry{1, 1}]; got != tt.status {
+				t.Errorf("status = %q, want %q", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestMemoryPickStoreSurvivorRules(t *testing.T) {
+	past := testNow.Add(-time.Hour)
+	tests := []struct {
+		name    string
+		setup   func(store *MemoryPickStore)
+		wantErr error
+	}{
+		{name: "not entered", setup: func(store *MemoryPickStore) {}, wantErr: ErrSurvivorNotEntered},
+		{
+			name: "eliminated",
+			setup: func(store *MemoryPickStore) {
+				store.survivorEntries[survivorEntry{1, 1}] = SurvivorEliminated
+			},
+			wantErr: ErrSurvivorEliminated,
+		},
+		{
+			name: "fighter used at another event",
+			setup: func(store *MemoryPickStore) {
+				store.EnterSurvivor(1, 1)
+				store.survivorPicks = append(store.survivorPicks, &SurvivorPick{ContestID: 1, UserID: 1, EventID: "e0", MatchupID: "m0", FighterID: "f1", Result: PickResultCorrect})
+			},
+			wantErr: ErrSurvivorFighterUsed,
+		},
+		{
+			name: "fighter freed by a cancelled pick",
+			setup: func(store *MemoryPickStore) {
+				store.EnterSurvivor(1, 1)
+				store.survivorPicks = append(store.survivorPicks, &SurvivorPick{ContestID: 1, UserID: 1, EventID: "e0", MatchupID: "m0", FighterID: "f1", Result: PickResultCancelled})
+			},
+		},
+		{
+			name: "fight locked",
+			setup: func(store *MemoryPickStore) {
+				store.EnterSurvivor(1, 1)
+				store.SetSchedule(MatchupSchedule{MatchupID: "m1", EventID: "e1", CardStartsAt: &past})
+			},
+			wantErr: ErrPickLocked,
+		},
+		{
+			name: "current pick locked",
+			setup: func(store *MemoryPickStore) {
+				store.EnterSurvivor(1, 1)
+				store.SetSchedule(MatchupSchedule{MatchupID: "m2", EventID: "e1", CardStartsAt: &past})
+				store.survivorPicks = append(store.survivorPicks, &SurvivorPick{ContestID: 1, UserID: 1, EventID: "e1", MatchupID: "m2", FighterID: "f2", Result: PickResultPending})
+			},
+			wantErr: ErrSurvivorPickLocked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			tt.setup(store)
+			if _, err := store.SubmitSurvivorPick(1, 1, "e1", "m1", "f1"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("SubmitSurvivorPick error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
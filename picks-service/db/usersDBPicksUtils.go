@@ -6,28 +6,67 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"sync"
 	"time"
 
 	_ "github.com/lib/pq"
 )
 
 var usersDb *sql.DB
-var (
-	pickAttempts = make(map[string]time.Time)
-	pickMutex    sync.Mutex
-	rateLimit    = 2 * time.Second
+
+// dbExecutor is satisfied by both *sql.DB and *sql.Tx, so writes can run inside or outside a transaction
+type dbExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// possible values of picks.pick_result
+const (
+	PickResultPending   = "pending"
+	PickResultCorrect   = "correct"
+	PickResultIncorrect = "incorrect"
+	PickResultVoid      = "void"
+	PickResultPush      = "push"
+	PickResultCancelled = "cancelled"
+)
+
+// possible values of matchup_results.outcome. draw_no_contest is used when the scraper doesn't say which
+const (
+	OutcomeWin           = "win"
+	OutcomeDraw          = "draw"
+	OutcomeNoContest     = "no_contest"
+	OutcomeDrawNoContest = "draw_no_contest"
+	OutcomeCancelled     = "cancelled"
 )
 
+// DrawOrNoContest is the Winner value the event scraper reports when neither fighter won
+const DrawOrNoContest = "Draw/No Contest"
+
 type Pick struct {
-	PickID             int    `json:"pick_id"`
-	UserID             int    `json:"user_id"`
-	MatchupID          string `json:"matchup_id"`
-	EventID            string `json:"event_id"`
-	SelectionFighterID string `json:"selection_fighter_id"`
-	PickResult         string `json:"pick_result"`
-	CreatedAt          string `json:"created_at"`
-	UpdatedAt          string `json:"updated_at"`
+	PickID             int      `json:"pick_id"`
+	UserID             int      `json:"user_id"`
+	MatchupID          string   `json:"matchup_id"`
+	EventID            string   `json:"event_id"`
+	SelectionFighterID string   `json:"selection_fighter_id"`
+	PickResult         string   `json:"pick_result"`
+	Confidence         *int     `json:"confidence,omitempty"`
+	Probability        *float64 `json:"probability,omitempty"`
+	OddsAtPick         *int     `json:"odds_at_pick"`
+	ClosingOdds        *int     `json:"closing_odds"`
+	CreatedAt          string   `json:"created_at"`
+	UpdatedAt          string   `json:"updated_at"`
+}
+
+type GradingSummary struct {
+	MatchupID       string `json:"matchup_id"`
+	EventID         string `json:"event_id"`
+	WinnerFighterID string `json:"winner_fighter_id"`
+	Correct         int    `json:"correct"`
+	Incorrect       int    `json:"incorrect"`
+	Void            int    `json:"void"`
+	Push            int    `json:"push"`
+	Cancelled       int    `json:"cancelled"`
+	Regraded        int    `json:"regraded"`
 }
 
 func StartUsersDbConnection() *sql.DB {
@@ -69,13 +108,23 @@ func InsertPick(user_id string, matchup_id string, event_id string, selection_fi
 	return nil
 }
 
-func GetPicksForUserAndEvent(userID int, eventID string) ([]Pick, error) {
-	sqlStatement := "SELECT pick_id, user_id, matchup_id, event_id, selection_fighter_id, pick_result, created_at, updated_at FROM public.picks WHERE user_id = $1 AND event_id = $2;"
+// pickColumns is the select list scanPicks expects
+const pickColumns = "pick_id, user_id, matchup_id, event_id, selection_fighter_id, pick_result, confidence, probability, odds_at_pick, closing_odds, created_at, updated_at"
 
-	rows, err := usersDb.Query(sqlStatement, userID, eventID)
-	if err != nil {
-		return nil, fmt.Errorf("error querying picks for user %d and event %s: %w", userID, eventID, err)
-	}
+func (s *PostgresPickStore) GetPicksForUserAndEvent(userID int, eventID string, q PickQuery) (PickPage, error) {
+	return s.listPicks("user_id = $1 AND event_id = $2", []interface{}{userID, eventID}, q)
+}
+
+func (s *PostgresPickStore) GetPicksForEvent(eventID string, q PickQuery) (PickPage, error) {
+	return s.listPicks("event_id = $1", []interface{}{eventID}, q)
+}
+
+func (s *PostgresPickStore) GetPicksForMatchup(matchupID string, q PickQuery) (PickPage, error) {
+	return s.listPicks("matchup_id = $1", []interface{}{matchupID}, q)
+}
+
+// scanPicks reads every row selected with pickColumns and closes rows
+func scanPicks(rows *sql.Rows) ([]Pick, error) {
 	defer rows.Close()
 
 	var picks []Pick
@@ -88,6 +137,10 @@ func GetPicksForUserAndEvent(userID int, eventID string) ([]Pick, error) {
 			&pick.EventID,
 			&pick.SelectionFighterID,
 			&pick.PickResult,
+			&pick.Confidence,
+			&pick.Probability,
+			&pick.OddsAtPick,
+			&pick.ClosingOdds,
 			&pick.CreatedAt,
 			&pick.UpdatedAt,
 		)
@@ -96,150 +149,320 @@ func GetPicksForUserAndEvent(userID int, eventID string) ([]Pick, error) {
 		}
 		picks = append(picks, pick)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pick rows: %w", err)
+	}
 
 	return picks, nil
 }
 
-func GetPicksForEvent(eventID string) ([]Pick, error) {
-	sqlStatement := "SELECT pick_id, user_id, matchup_id, event_id, selection_fighter_id, pick_result, created_at, updated_at FROM public.picks WHERE event_id = $1;"
+// insert or update pick (to handle if someone switches their pick). meta.OverrideLock lets admins fix picks after lock.
+// probability is the user's win probability for the selected fighter; nil makes it a plain binary pick
+func (s *PostgresPickStore) UpsertPick(user_id string, matchup_id string, event_id string, selection_fighter_id string, probability *float64, meta PickMeta) error {
+	if err := ValidatePickProbability(probability); err != nil {
+		return err
+	}
+	if err := validatePickWith(s.events, event_id, matchup_id, selection_fighter_id); err != nil {
+		return err
+	}
+
+	if !meta.OverrideLock {
+		if err := checkPickLock(s.db, s.lockMode, matchup_id, time.Now()); err != nil {
+			return err
+		}
+	} else {
+		log.Printf("Lock override used for pick - userId: %s, matchupId: %s", user_id, matchup_id)
+	}
+
+	//odds are re-quoted on every change since the pick is now made at the current price.
+	//quoted before the transaction so a slow odds source doesn't hold locks
+	oddsAtPick := lookupPickOdds(s.odds, matchup_id, selection_fighter_id)
 
-	rows, err := usersDb.Query(sqlStatement, eventID)
+	tx, err := s.db.BeginTx(context.Background(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("error querying picks for event %s: %w", eventID, err)
+		return fmt.Errorf("unable to start pick transaction: %w", err)
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var picks []Pick
-	for rows.Next() {
-		var pick Pick
-		err := rows.Scan(
-			&pick.PickID,
-			&pick.UserID,
-			&pick.MatchupID,
-			&pick.EventID,
-			&pick.SelectionFighterID,
-			&pick.PickResult,
-			&pick.CreatedAt,
-			&pick.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("error scanning pick row: %w", err)
-		}
-		picks = append(picks, pick)
+	if _, _, err := s.writePick(tx, user_id, matchup_id, event_id, selection_fighter_id, oddsAtPick, meta); err != nil {
+		return err
 	}
 
-	return picks, nil
+	sqlProbability := "UPDATE public.picks SET probability = $1 WHERE user_id = $2 AND matchup_id = $3 AND event_id = $4;"
+	if _, err := tx.ExecContext(context.Background(), sqlProbability, probability, user_id, matchup_id, event_id); err != nil {
+		return fmt.Errorf("unable to set pick probability: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit pick: %w", err)
+	}
+	invalidateConsensus(matchup_id, event_id)
+	return nil
 }
 
-func GetPicksForMatchup(matchupID string) ([]Pick, error) {
-	sqlStatement := "SELECT pick_id, user_id, matchup_id, event_id, selection_fighter_id, pick_result, created_at, updated_at FROM public.picks WHERE matchup_id = $1;"
+// writePick inserts the pick or updates the selection of an existing one, without any rate limit checks,
+// and appends the change to pick_history. It is a single upsert on the (user_id, matchup_id, event_id) key, so
+// concurrent submissions can't create duplicates. oddsAtPick must be quoted by the caller before its transaction
+// starts, since odds sources can be slow. inserted reports which of the two happened and previous is the
+// selection it replaced
+func (s *PostgresPickStore) writePick(ex dbExecutor, user_id string, matchup_id string, event_id string, selection_fighter_id string, oddsAtPick *int, meta PickMeta) (inserted bool, previous string, err error) {
+	//checked again here since the caller's earlier check ran before the transaction started
+	if !meta.OverrideLock {
+		if err := checkPickLock(ex, s.lockMode, matchup_id, time.Now()); err != nil {
+			return false, "", err
+		}
+	}
 
-	rows, err := usersDb.Query(sqlStatement, matchupID)
+	//existing reads the row as it was before this statement; xmax is 0 only on a freshly inserted row.
+	//a pick cancelled by a rebooked opponent counts again once the user picks the new fight.
+	//a probability was given for the old selection, so switching sides drops it
+	sqlUpsert := `WITH existing AS (
+			SELECT selection_fighter_id FROM public.picks
+			WHERE user_id = $1 AND matchup_id = $2 AND event_id = $3
+		)
+		INSERT INTO public.picks AS p (user_id, matchup_id, event_id, selection_fighter_id, odds_at_pick)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, matchup_id, event_id) DO UPDATE SET
+			selection_fighter_id = EXCLUDED.selection_fighter_id,
+			odds_at_pick = EXCLUDED.odds_at_pick,
+			updated_at = CURRENT_TIMESTAMP,
+			pick_result = CASE WHEN p.pick_result = $6 THEN $7 ELSE p.pick_result END,
+			probability = CASE WHEN p.selection_fighter_id = EXCLUDED.selection_fighter_id THEN p.probability ELSE NULL END
+		RETURNING p.pick_id, (p.xmax = 0), COALESCE((SELECT selection_fighter_id FROM existing), '');`
+	var pickId int
+	err = ex.QueryRowContext(context.Background(), sqlUpsert, user_id, matchup_id, event_id, selection_fighter_id, oddsAtPick,
+		PickResultCancelled, PickResultPending).Scan(&pickId, &inserted, &previous)
 	if err != nil {
-		return nil, fmt.Errorf("error querying picks for matchup %s: %w", matchupID, err)
+		log.Printf("Error upserting pick: %v", err)
+		return false, "", fmt.Errorf("unable to upsert pick: %w", err)
 	}
-	defer rows.Close()
 
-	var picks []Pick
-	for rows.Next() {
-		var pick Pick
-		err := rows.Scan(
-			&pick.PickID,
-			&pick.UserID,
-			&pick.MatchupID,
-			&pick.EventID,
-			&pick.SelectionFighterID,
-			&pick.PickResult,
-			&pick.CreatedAt,
-			&pick.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("error scanning pick row: %w", err)
-		}
-		picks = append(picks, pick)
+	//re-submitting the same fighter isn't a change. when a concurrent insert won the race the snapshot
+	//didn't see it, so previous is empty and the update is recorded without a previous selection
+	if !inserted && previous == selection_fighter_id {
+		return false, previous, nil
 	}
+	return inserted, previous, pickChanged(ex, pickId, user_id, matchup_id, event_id, inserted, previous, selection_fighter_id, meta)
+}
 
-	return picks, nil
+// pickChanged records a new or switched pick in pick_history and tells live listeners the event's counts moved
+func pickChanged(ex dbExecutor, pick_id int, user_id string, matchup_id string, event_id string, inserted bool, previous string, selection string, meta PickMeta) error {
+	if err := recordPickChange(ex, pick_id, user_id, matchup_id, event_id, inserted, previous, selection, meta); err != nil {
+		return err
+	}
+	return notifyEventUpdate(ex, EventUpdate{Type: UpdatePicks, EventID: event_id, MatchupID: matchup_id})
 }
 
-// insert or update pick (to handle if someone switches their pick)
-func UpsertPick(user_id string, matchup_id string, event_id string, selection_fighter_id string) error {
-	// Rate limiting check
-	pickMutex.Lock()
-	key := fmt.Sprintf("%s:%s:%s", user_id, matchup_id, event_id)
-	lastAttempt, exists := pickAttempts[key]
-	now := time.Now()
-
-	if exists {
-		timeRemaining := rateLimit - now.Sub(lastAttempt)
-		if timeRemaining > 0 {
-			pickMutex.Unlock()
-			log.Printf("Rate limit hit - Time remaining: %.1f seconds", timeRemaining.Seconds())
-			return fmt.Errorf("rate limit exceeded: please wait %.1f seconds before updating your pick again", timeRemaining.Seconds())
+// UpdateMatchupPickResults grades the picks for a matchup and keeps the per-user counters in step.
+// An empty winning_fighter_id means nobody won: a draw pushes the picks, a no contest voids them.
+// Sending a different result later regrades the picks and reverses the counters they had added
+func (s *PostgresPickStore) UpdateMatchupPickResults(winning_fighter_id string, event_id string, matchup_id string, result string) (GradingSummary, error) {
+	summary := GradingSummary{MatchupID: matchup_id, EventID: event_id, WinnerFighterID: winning_fighter_id}
+
+	fightResult, err := ParseFightResult(result)
+	if err != nil {
+		//a result we can't parse still grades the moneyline, props just get voided
+		log.Printf("Unable to parse result for matchup %s, voiding props: %v", matchup_id, err)
+	}
+
+	outcome := OutcomeWin
+	noWinnerResult := PickResultVoid
+	if winning_fighter_id == "" {
+		fightResult = FightResult{}
+		outcome = NoWinnerOutcome(result)
+		if outcome == OutcomeDraw {
+			noWinnerResult = PickResultPush
 		}
 	}
 
-	pickAttempts[key] = now
-	pickMutex.Unlock()
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return summary, fmt.Errorf("unable to start grading transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := recordMatchupResult(tx, matchup_id, event_id, winning_fighter_id, outcome, result, fightResult); err != nil {
+		return summary, err
+	}
+
+	if err := recordClosingOdds(tx, matchup_id, event_id); err != nil {
+		return summary, err
+	}
 
-	// Add debug logging
-	log.Printf("Checking for existing pick...")
+	if err := gradePicks(tx, matchup_id, event_id, winning_fighter_id, noWinnerResult, &summary); err != nil {
+		return summary, err
+	}
 
-	//check if the pick already exists
-	sqlCheck := "SELECT pick_id FROM public.picks WHERE user_id = $1 AND matchup_id = $2 AND event_id = $3;"
-	var pickId string
-	err := usersDb.QueryRow(sqlCheck, user_id, matchup_id, event_id).Scan(&pickId)
+	if err := gradeSurvivorPicks(tx, matchup_id, event_id, winning_fighter_id, noWinnerResult); err != nil {
+		return summary, err
+	}
+
+	//props are graded after the moneyline since they depend on it
+	if err := gradePropPicks(tx, matchup_id, event_id, fightResult); err != nil {
+		return summary, err
+	}
+
+	if err := notifyEventUpdate(tx, EventUpdate{Type: UpdateResult, EventID: event_id, MatchupID: matchup_id}); err != nil {
+		return summary, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return summary, fmt.Errorf("unable to commit grading for matchup %s: %w", matchup_id, err)
+	}
+
+	log.Printf("Graded matchup %s: %d correct, %d incorrect, %d void, %d push, %d regraded", matchup_id,
+		summary.Correct, summary.Incorrect, summary.Void, summary.Push, summary.Regraded)
 
+	//achievements are best effort and evaluated in the background, a failed rule never fails the grading
+	queueAchievements(matchup_id)
+	return summary, nil
+}
+
+type MatchupResult struct {
+	MatchupID       string    `json:"matchup_id"`
+	EventID         string    `json:"event_id"`
+	WinnerFighterID *string   `json:"winner_fighter_id"`
+	Outcome         string    `json:"outcome"`
+	Method          *string   `json:"method"`
+	Round           *int      `json:"round"`
+	GradedAt        time.Time `json:"graded_at"`
+}
+
+// GetMatchupResult returns the recorded result of a matchup, or nil if it hasn't been graded
+func GetMatchupResult(matchup_id string) (*MatchupResult, error) {
+	var result MatchupResult
+	sqlStatement := `SELECT matchup_id, event_id, winner_fighter_id, outcome, method, round, graded_at
+		FROM public.matchup_results WHERE matchup_id = $1;`
+	err := usersDb.QueryRow(sqlStatement, matchup_id).Scan(&result.MatchupID, &result.EventID, &result.WinnerFighterID,
+		&result.Outcome, &result.Method, &result.Round, &result.GradedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Printf("No existing pick found, inserting new pick...")
-			//if no existing pick, insert a new one
-			sqlInsert := "INSERT INTO public.picks (user_id, matchup_id, event_id, selection_fighter_id) VALUES ($1, $2, $3, $4);"
-			_, err := usersDb.ExecContext(context.Background(), sqlInsert, user_id, matchup_id, event_id, selection_fighter_id)
-			if err != nil {
-				log.Printf("Error inserting pick: %v", err)
-				return fmt.Errorf("unable to insert pick: %w", err)
-			}
-		} else {
-			log.Printf("Error checking for existing pick: %v", err)
-			return fmt.Errorf("error checking for existing pick: %w", err)
-		}
-	} else {
-		log.Printf("Existing pick found, updating pick...")
-		//if a pick exists, update the selection
-		sqlUpdate := "UPDATE public.picks SET selection_fighter_id = $1, updated_at = CURRENT_TIMESTAMP WHERE pick_id = $2;"
-		_, err := usersDb.ExecContext(context.Background(), sqlUpdate, selection_fighter_id, pickId)
-		if err != nil {
-			log.Printf("Error updating pick: %v", err)
-			return fmt.Errorf("unable to update pick: %w", err)
-		}
+		return nil, fmt.Errorf("error retrieving result for matchup %s: %w", matchup_id, err)
 	}
+	return &result, nil
+}
 
+// recordMatchupResult stores the result so a matchup is only ever graded against one winner, and locks its picks
+func recordMatchupResult(tx *sql.Tx, matchup_id string, event_id string, winning_fighter_id string, outcome string, result string, fightResult FightResult) error {
+	sqlResult := `INSERT INTO public.matchup_results (matchup_id, event_id, winner_fighter_id, outcome, result_text, method, round)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, 0))
+		ON CONFLICT (matchup_id) DO UPDATE SET
+			winner_fighter_id = EXCLUDED.winner_fighter_id,
+			outcome = EXCLUDED.outcome,
+			result_text = EXCLUDED.result_text,
+			method = EXCLUDED.method,
+			round = EXCLUDED.round,
+			graded_at = CURRENT_TIMESTAMP;`
+	_, err := tx.ExecContext(context.Background(), sqlResult, matchup_id, event_id, winning_fighter_id, outcome,
+		result, fightResult.Method, fightResult.Round)
+	if err != nil {
+		return fmt.Errorf("unable to record result for matchup %s: %w", matchup_id, err)
+	}
 	return nil
 }
 
-func UpdateMatchupPickResults(winning_fighter_id string, event_id string, matchup_id string) error {
-	//select all picks in picks db with pick result = 'pending' and matchup_id = '$matchup_id' and event_id = '$event_id'
+// gradePicks sets the result of every pick on a matchup: correct / incorrect against winning_fighter_id, or
+// noWinnerResult when it is empty. Only picks whose result actually changes are touched, so re-sending the
+// same result never double counts, and the users' counters move by the difference between old and new result.
+// Cancelled picks are final and never regraded, and cancelling one is recorded in pick_history
+func gradePicks(tx *sql.Tx, matchup_id string, event_id string, winning_fighter_id string, noWinnerResult string, summary *GradingSummary) error {
+	sqlGrade := `WITH graded AS (
+			SELECT pick_id, pick_result AS previous,
+				CASE
+					WHEN $1 = '' THEN $4
+					WHEN selection_fighter_id = $1 THEN $5
+					ELSE $6
+				END AS next
+			FROM public.picks
+			WHERE matchup_id = $2 AND event_id = $3 AND pick_result <> $7
+		),
+		changed AS (
+			UPDATE public.picks p SET pick_result = g.next, updated_at = CURRENT_TIMESTAMP
+			FROM graded g
+			WHERE p.pick_id = g.pick_id AND g.previous <> g.next
+			RETURNING p.pick_id, p.user_id, p.matchup_id, p.event_id, p.selection_fighter_id, g.previous, g.next
+		),
+		history AS (
+			INSERT INTO public.pick_history (pick_id, user_id, matchup_id, event_id, previous_selection_fighter_id,
+				new_selection_fighter_id, change_type, source)
+			SELECT pick_id, user_id, matchup_id, event_id, selection_fighter_id, selection_fighter_id, $8, $9
+			FROM changed WHERE next = $7
+		)
+		SELECT user_id, previous, next FROM changed;`
+	rows, err := tx.QueryContext(context.Background(), sqlGrade, winning_fighter_id, matchup_id, event_id,
+		noWinnerResult, PickResultCorrect, PickResultIncorrect, PickResultCancelled, PickChangeCancel, PickSourceAdmin)
+	if err != nil {
+		return fmt.Errorf("unable to grade picks for matchup %s: %w", matchup_id, err)
+	}
+
+	type userTally struct{ correct, total int }
+	tallies := make(map[int]*userTally)
+	for rows.Next() {
+		var userId int
+		var previous, next string
+		if err := rows.Scan(&userId, &previous, &next); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning graded pick row: %w", err)
+		}
+
+		tally, ok := tallies[userId]
+		if !ok {
+			tally = &userTally{}
+			tallies[userId] = tally
+		}
 
-	//for each pick
-	//if pick.selection_fighter_id == winning_fighter_id, set pick.pick_result to 'correct', else set pick to 'incorrect'
+		//only correct and incorrect picks count, so void / push / cancelled picks add nothing
+		prevCorrect, prevTotal := countedResult(previous)
+		nextCorrect, nextTotal := countedResult(next)
+		tally.correct += nextCorrect - prevCorrect
+		tally.total += nextTotal - prevTotal
 
+		if previous != PickResultPending {
+			summary.Regraded++
+		}
+		switch next {
+		case PickResultCorrect:
+			summary.Correct++
+		case PickResultIncorrect:
+			summary.Incorrect++
+		case PickResultVoid:
+			summary.Void++
+		case PickResultPush:
+			summary.Push++
+		case PickResultCancelled:
+			summary.Cancelled++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return fmt.Errorf("error reading graded picks: %w", err)
+	}
+	rows.Close()
+
+	sqlCounters := `UPDATE public.users
+		SET total_picks = COALESCE(total_picks, 0) + $1, total_correct_picks = COALESCE(total_correct_picks, 0) + $2
+		WHERE user_id = $3;`
+	for userId, tally := range tallies {
+		if tally.total == 0 && tally.correct == 0 {
+			continue
+		}
+		_, err := tx.ExecContext(context.Background(), sqlCounters, tally.total, tally.correct, userId)
+		if err != nil {
+			return fmt.Errorf("unable to update pick counters for user %d: %w", userId, err)
+		}
+	}
 	return nil
 }
 
-func init() {
-	// Clean up old rate limit entries every hour
-	go func() {
-		for {
-			time.Sleep(1 * time.Hour)
-			pickMutex.Lock()
-			now := time.Now()
-			for key, timestamp := range pickAttempts {
-				if now.Sub(timestamp) > 24*time.Hour {
-					delete(pickAttempts, key)
-				}
-			}
-			pickMutex.Unlock()
-		}
-	}()
+// countedResult is what a pick result adds to a user's total_correct_picks and total_picks
+func countedResult(result string) (correct int, total int) {
+	switch result {
+	case PickResultCorrect:
+		return 1, 1
+	case PickResultIncorrect:
+		return 0, 1
+	}
+	return 0, 0
 }
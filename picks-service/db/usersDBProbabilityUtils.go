@@ -0,0 +1,132 @@
+// usersDBProbabilityUtils scores probability picks with Brier score and log-loss and builds calibration curves
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrInvalidProbability = errors.New("invalid pick probability")
+
+// a probability is for the fighter the user picked, so it's at least a coin flip. certainty is not
+// allowed since a single wrong pick at 1.0 would make log-loss infinite
+const (
+	MinPickProbability = 0.5
+	MaxPickProbability = 0.99
+)
+
+// number of equal width bins the calibration curve splits [MinPickProbability, 1] into
+const calibrationBins = 5
+
+type ProbabilityStats struct {
+	UserID     int                `json:"user_id,omitempty"`
+	Picks      int                `json:"picks"`
+	Correct    int                `json:"correct"`
+	Accuracy   float64            `json:"accuracy"`
+	BrierScore float64            `json:"brier_score"`
+	LogLoss    float64            `json:"log_loss"`
+	Curve      []CalibrationPoint `json:"calibration"`
+}
+
+// CalibrationPoint compares the average probability users gave in a bin with how often those picks won.
+// a well calibrated forecaster has MeanProbability close to ObservedRate in every bin
+type CalibrationPoint struct {
+	Lower           float64 `json:"lower"`
+	Upper           float64 `json:"upper"`
+	Picks           int     `json:"picks"`
+	MeanProbability float64 `json:"mean_probability"`
+	ObservedRate    float64 `json:"observed_rate"`
+}
+
+// ValidatePickProbability accepts nil (a binary pick) or a probability within the allowed range
+func ValidatePickProbability(probability *float64) error {
+	if probability == nil {
+		return nil
+	}
+	p := *probability
+	if math.IsNaN(p) || p < MinPickProbability || p > MaxPickProbability {
+		return fmt.Errorf("%w: must be between %.2f and %.2f", ErrInvalidProbability, MinPickProbability, MaxPickProbability)
+	}
+	return nil
+}
+
+// GetProbabilityStats scores graded probability picks. userID 0 scores every user together, which gives
+// the community curve to hold the prediction-service model against
+func GetProbabilityStats(userID int) (ProbabilityStats, error) {
+	stats := ProbabilityStats{UserID: userID}
+
+	sqlStatement := `SELECT probability, pick_result = $2 FROM public.picks
+		WHERE probability IS NOT NULL AND pick_result IN ($2, $3) AND ($1 = 0 OR user_id = $1);`
+	rows, err := usersDb.Query(sqlStatement, userID, PickResultCorrect, PickResultIncorrect)
+	if err != nil {
+		return stats, fmt.Errorf("error querying probability picks: %w", err)
+	}
+	defer rows.Close()
+
+	width := (1 - MinPickProbability) / calibrationBins
+	bins := make([]struct {
+		picks, correct int
+		probabilities  float64
+	}, calibrationBins)
+
+	var brier, logLoss float64
+	for rows.Next() {
+		var p float64
+		var correct bool
+		if err := rows.Scan(&p, &correct); err != nil {
+			return stats, fmt.Errorf("error scanning probability pick row: %w", err)
+		}
+
+		outcome := 0.0
+		if correct {
+			outcome = 1
+			stats.Correct++
+		}
+		stats.Picks++
+		brier += (p - outcome) * (p - outcome)
+		if correct {
+			logLoss -= math.Log(p)
+		} else {
+			logLoss -= math.Log(1 - p)
+		}
+
+		bin := int((p - MinPickProbability) / width)
+		if bin >= calibrationBins {
+			bin = calibrationBins - 1
+		}
+		if bin < 0 {
+			bin = 0
+		}
+		bins[bin].picks++
+		bins[bin].probabilities += p
+		if correct {
+			bins[bin].correct++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return stats, fmt.Errorf("error iterating probability pick rows: %w", err)
+	}
+
+	stats.Accuracy = accuracy(stats.Correct, stats.Picks)
+	if stats.Picks > 0 {
+		stats.BrierScore = brier / float64(stats.Picks)
+		stats.LogLoss = logLoss / float64(stats.Picks)
+	}
+
+	stats.Curve = make([]CalibrationPoint, calibrationBins)
+	for i, b := range bins {
+		point := CalibrationPoint{
+			Lower: MinPickProbability + float64(i)*width,
+			Upper: MinPickProbability + float64(i+1)*width,
+			Picks: b.picks,
+		}
+		if b.picks > 0 {
+			point.MeanProbability = b.probabilities / float64(b.picks)
+			point.ObservedRate = accuracy(b.correct, b.picks)
+		}
+		stats.Curve[i] = point
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,256 @@
+// usersDBPropPicksUtils handles method of victory and round props, which ride on top of the moneyline pick
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// methods a prop can be placed on
+const (
+	MethodKOTKO      = "KO/TKO"
+	MethodSubmission = "SUB"
+	MethodDecision   = "DEC"
+)
+
+// longest scheduled fight (title fights and main events). used when the schedule doesn't say
+const MaxScheduledRounds = 5
+
+var ErrInvalidProp = errors.New("invalid prop pick")
+
+type PropPick struct {
+	PropPickID   int     `json:"prop_pick_id"`
+	UserID       int     `json:"user_id"`
+	MatchupID    string  `json:"matchup_id"`
+	EventID      string  `json:"event_id"`
+	Method       string  `json:"method"`
+	Round        *int    `json:"round"`
+	MethodResult string  `json:"method_result"`
+	RoundResult  *string `json:"round_result"`
+	CreatedAt    string  `json:"created_at"`
+	UpdatedAt    string  `json:"updated_at"`
+}
+
+// FightResult is the structured form of the event scraper's Result string. Method is empty when the
+// bout had no finish method to grade against (draw, no contest, DQ or unknown)
+type FightResult struct {
+	Method string
+	Round  int
+}
+
+// after "Final" and whitespace are stripped and the string is upper cased, e.g. "KO/TKOR1,0:21" or "UDECR3,5:00"
+var resultPattern = regexp.MustCompile(`^(KO/TKO|TKO|KO|SUBMISSION|SUB|[USM]?DEC(?:ISION)?|DQ|NC|DRAW)(?:R(\d+))?`)
+
+// ParseFightResult turns a result such as "FinalKO/TKOR1, 0:21", "FinalSubR2, 3:10" or "FinalU DecR3, 5:00"
+// into a method and round
+func ParseFightResult(result string) (FightResult, error) {
+	compact := strings.ToUpper(strings.Join(strings.Fields(result), ""))
+	compact = strings.TrimPrefix(compact, "FINAL")
+
+	match := resultPattern.FindStringSubmatch(compact)
+	if match == nil {
+		return FightResult{}, fmt.Errorf("unrecognised result %q", result)
+	}
+
+	var fr FightResult
+	switch {
+	case match[1] == "KO/TKO" || match[1] == "TKO" || match[1] == "KO":
+		fr.Method = MethodKOTKO
+	case strings.HasPrefix(match[1], "SUB"):
+		fr.Method = MethodSubmission
+	case strings.Contains(match[1], "DEC"):
+		fr.Method = MethodDecision
+	default:
+		return FightResult{}, nil
+	}
+
+	if match[2] != "" {
+		fr.Round, _ = strconv.Atoi(match[2])
+	}
+	return fr, nil
+}
+
+// NormalizeMethod maps user input ("ko", "tko", "submission", "decision"...) to one of the Method constants
+func NormalizeMethod(method string) (string, bool) {
+	switch strings.ToUpper(strings.TrimSpace(method)) {
+	case "KO/TKO", "KO", "TKO":
+		return MethodKOTKO, true
+	case "SUB", "SUBMISSION":
+		return MethodSubmission, true
+	case "DEC", "DECISION":
+		return MethodDecision, true
+	}
+	return "", false
+}
+
+// ValidatePropPick checks the method and round against the scheduled length of the fight
+func ValidatePropPick(method string, round *int, scheduledRounds int) error {
+	if _, ok := NormalizeMethod(method); !ok {
+		return fmt.Errorf("%w: method must be one of %s, %s or %s", ErrInvalidProp, MethodKOTKO, MethodSubmission, MethodDecision)
+	}
+	if round == nil {
+		return nil
+	}
+
+	normalized, _ := NormalizeMethod(method)
+	if normalized == MethodDecision {
+		return fmt.Errorf("%w: a decision can't be picked for a specific round", ErrInvalidProp)
+	}
+	if *round < 1 || *round > scheduledRounds {
+		return fmt.Errorf("%w: round must be between 1 and %d", ErrInvalidProp, scheduledRounds)
+	}
+	return nil
+}
+
+// scheduledRoundsForMatchup returns the synced number of rounds, falling back to MaxScheduledRounds
+// so a missing schedule never rejects a valid pick
+func scheduledRoundsForMatchup(ex dbExecutor, matchup_id string) (int, error) {
+	var rounds sql.NullInt64
+	sqlStatement := "SELECT scheduled_rounds FROM public.matchup_schedule WHERE matchup_id = $1;"
+	err := ex.QueryRowContext(context.Background(), sqlStatement, matchup_id).Scan(&rounds)
+	if err == sql.ErrNoRows || (err == nil && !rounds.Valid) {
+		return MaxScheduledRounds, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error retrieving scheduled rounds for matchup %s: %w", matchup_id, err)
+	}
+	return int(rounds.Int64), nil
+}
+
+// UpsertPropPick inserts or replaces a user's method / round prop for a matchup, after checking the matchup is on the
+// event's card like moneyline picks are
+func (s *PostgresPickStore) UpsertPropPick(user_id int, matchup_id string, event_id string, method string, round *int, overrideLock bool) error {
+	if err := validateMatchupWith(s.events, event_id, matchup_id); err != nil {
+		return err
+	}
+	if !overrideLock {
+		if err := checkPickLock(s.db, s.lockMode, matchup_id, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	scheduledRounds, err := scheduledRoundsForMatchup(s.db, matchup_id)
+	if err != nil {
+		return err
+	}
+	if err := ValidatePropPick(method, round, scheduledRounds); err != nil {
+		return err
+	}
+	method, _ = NormalizeMethod(method)
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("unable to start prop pick transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	//re-checked inside the transaction so a prop sent right at lock time can't slip in
+	if !overrideLock {
+		if err := checkPickLock(tx, s.lockMode, matchup_id, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	//a prop cancelled by a rebook that kept the matchup id counts again once it is resubmitted
+	sqlUpsert := `INSERT INTO public.prop_picks (user_id, matchup_id, event_id, method, round, round_result)
+		VALUES ($1, $2, $3, $4, $5, CASE WHEN $5::integer IS NULL THEN NULL ELSE $6 END)
+		ON CONFLICT (user_id, matchup_id, event_id) DO UPDATE SET
+			method = EXCLUDED.method,
+			round = EXCLUDED.round,
+			method_result = $6,
+			round_result = EXCLUDED.round_result;`
+	_, err = tx.ExecContext(context.Background(), sqlUpsert, user_id, matchup_id, event_id, method, round, PickResultPending)
+	if err != nil {
+		return fmt.Errorf("unable to upsert prop pick: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit prop pick: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresPickStore) GetPropPicksForUserAndEvent(userID int, eventID string) ([]PropPick, error) {
+	sqlStatement := "SELECT prop_pick_id, user_id, matchup_id, event_id, method, round, method_result, round_result, created_at, updated_at FROM public.prop_picks WHERE user_id = $1 AND event_id = $2;"
+
+	rows, err := s.db.Query(sqlStatement, userID, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying prop picks for user %d and event %s: %w", userID, eventID, err)
+	}
+	defer rows.Close()
+
+	var props []PropPick
+	for rows.Next() {
+		var prop PropPick
+		var round sql.NullInt64
+		var roundResult sql.NullString
+		err := rows.Scan(
+			&prop.PropPickID,
+			&prop.UserID,
+			&prop.MatchupID,
+			&prop.EventID,
+			&prop.Method,
+			&round,
+			&prop.MethodResult,
+			&roundResult,
+			&prop.CreatedAt,
+			&prop.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning prop pick row: %w", err)
+		}
+		if round.Valid {
+			r := int(round.Int64)
+			prop.Round = &r
+		}
+		if roundResult.Valid {
+			prop.RoundResult = &roundResult.String
+		}
+		props = append(props, prop)
+	}
+
+	return props, nil
+}
+
+// gradePropPicks (re)grades the props for a matchup inside the grading transaction. A prop only
+// counts when the user's moneyline pick on the same matchup was correct. Round props are voided
+// when the result didn't say which round the finish came in
+func gradePropPicks(tx *sql.Tx, matchup_id string, event_id string, result FightResult) error {
+	sqlGrade := `UPDATE public.prop_picks pp SET
+			method_result = CASE
+				WHEN $3 = '' THEN $5
+				WHEN p.pick_result = $6 AND pp.method = $3 THEN $6
+				ELSE $7
+			END,
+			round_result = CASE
+				WHEN pp.round IS NULL THEN NULL
+				WHEN $3 = '' OR ($3 <> $8 AND $4 = 0) THEN $5
+				WHEN p.pick_result = $6 AND $3 <> $8 AND pp.round = $4 THEN $6
+				ELSE $7
+			END
+		FROM public.picks p
+		WHERE p.user_id = pp.user_id AND p.matchup_id = pp.matchup_id AND p.event_id = pp.event_id
+			AND pp.matchup_id = $1 AND pp.event_id = $2 AND pp.method_result <> $9;`
+	_, err := tx.ExecContext(context.Background(), sqlGrade, matchup_id, event_id, result.Method, result.Round,
+		PickResultVoid, PickResultCorrect, PickResultIncorrect, MethodDecision, PickResultCancelled)
+	if err != nil {
+		return fmt.Errorf("unable to grade prop picks for matchup %s: %w", matchup_id, err)
+	}
+
+	//props without a moneyline pick have nothing to ride on
+	sqlOrphans := `UPDATE public.prop_picks pp
+		SET method_result = $3, round_result = CASE WHEN pp.round IS NULL THEN NULL ELSE $3 END
+		WHERE pp.matchup_id = $1 AND pp.event_id = $2 AND pp.method_result <> $4
+			AND NOT EXISTS (SELECT 1 FROM public.picks p
+				WHERE p.user_id = pp.user_id AND p.matchup_id = pp.matchup_id AND p.event_id = pp.event_id);`
+	_, err = tx.ExecContext(context.Background(), sqlOrphans, matchup_id, event_id, PickResultVoid, PickResultCancelled)
+	if err != nil {
+		return fmt.Errorf("unable to void orphaned prop picks for matchup %s: %w", matchup_id, err)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// usersDBRemindersUtils finds events about to lock and the users who still have fights to pick for them
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ReminderActiveWindow limits reminders to users who made a pick this recently, so inactive accounts aren't nagged
+const ReminderActiveWindow = 90 * 24 * time.Hour
+
+// UpcomingLock is when the first fight of an event locks
+type UpcomingLock struct {
+	EventID   string
+	EventName string
+	LocksAt   time.Time
+}
+
+// ReminderRecipient is a user with an incomplete card for an event
+type ReminderRecipient struct {
+	UserID   int
+	Username string
+	Email    string
+	Picked   int
+	Matchups int
+}
+
+// GetUpcomingLocks returns the events whose first lock under mode falls after now and no later than now+within,
+// soonest first. Matchups that already have a result are left out
+func GetUpcomingLocks(mode LockMode, now time.Time, within time.Duration) ([]UpcomingLock, error) {
+	sqlStatement := `SELECT event_id, COALESCE(event_name, ''), card_starts_at, fight_starts_at
+		FROM public.matchup_schedule ms
+		WHERE NOT EXISTS (SELECT 1 FROM public.matchup_results mr WHERE mr.matchup_id = ms.matchup_id)
+			AND event_id IN (
+				SELECT event_id FROM public.matchup_schedule
+				WHERE card_starts_at BETWEEN $1 AND $2 OR fight_starts_at BETWEEN $1 AND $2
+			);`
+	rows, err := usersDb.Query(sqlStatement, now, now.Add(within))
+	if err != nil {
+		return nil, fmt.Errorf("error querying upcoming schedules: %w", err)
+	}
+	defer rows.Close()
+
+	//an event locks with its earliest matchup, so a card whose prelims already started is past its lock
+	locks := make(map[string]*UpcomingLock)
+	for rows.Next() {
+		var s MatchupSchedule
+		var cardStartsAt, fightStartsAt sql.NullTime
+		if err := rows.Scan(&s.EventID, &s.EventName, &cardStartsAt, &fightStartsAt); err != nil {
+			return nil, fmt.Errorf("error scanning schedule row: %w", err)
+		}
+		if cardStartsAt.Valid {
+			s.CardStartsAt = &cardStartsAt.Time
+		}
+		if fightStartsAt.Valid {
+			s.FightStartsAt = &fightStartsAt.Time
+		}
+
+		lockAt, ok := s.LockTime(mode)
+		if !ok {
+			continue
+		}
+		lock, seen := locks[s.EventID]
+		if !seen {
+			lock = &UpcomingLock{EventID: s.EventID, EventName: s.EventName, LocksAt: lockAt}
+			locks[s.EventID] = lock
+		}
+		if lockAt.Before(lock.LocksAt) {
+			lock.LocksAt = lockAt
+		}
+		if lock.EventName == "" {
+			lock.EventName = s.EventName
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schedule rows: %w", err)
+	}
+
+	var upcoming []UpcomingLock
+	for _, lock := range locks {
+		if lock.LocksAt.After(now) && !lock.LocksAt.After(now.Add(within)) {
+			upcoming = append(upcoming, *lock)
+		}
+	}
+	sort.Slice(upcoming, func(i, j int) bool { return upcoming[i].LocksAt.Before(upcoming[j].LocksAt) })
+	return upcoming, nil
+}
+
+// ClaimReminderRecipients finds the active users who haven't picked every fight of the event and records the
+// reminder for the given offset as sent to them, in one statement. Bouts that already have a result, which
+// includes cancelled ones, are left off the card like in GetUpcomingLocks. Users already claimed for this
+// offset, by this or another instance, are not returned again
+func ClaimReminderRecipients(event_id string, offset time.Duration, now time.Time) ([]ReminderRecipient, error) {
+	sqlStatement := `WITH open_matchups AS (
+			SELECT ms.matchup_id FROM public.matchup_schedule ms
+			WHERE ms.event_id = $1
+				AND NOT EXISTS (SELECT 1 FROM public.matchup_results mr WHERE mr.matchup_id = ms.matchup_id)
+		),
+		card AS (
+			SELECT COUNT(*) AS matchups FROM open_matchups
+		),
+		incomplete AS (
+			SELECT u.user_id, u.username, u.email,
+				(SELECT COUNT(*) FROM public.picks p
+					WHERE p.user_id = u.user_id AND p.event_id = $1 AND p.pick_result <> $4
+						AND p.matchup_id IN (SELECT matchup_id FROM open_matchups)) AS picked
+			FROM public.users u
+			WHERE EXISTS (SELECT 1 FROM public.picks r WHERE r.user_id = u.user_id AND r.created_at >= $3)
+		),
+		claimed AS (
+			INSERT INTO public.pick_reminders (user_id, event_id, offset_minutes)
+			SELECT i.user_id, $1, $2 FROM incomplete i, card WHERE i.picked < card.matchups
+			ON CONFLICT (user_id, event_id, offset_minutes) DO NOTHING
+			RETURNING user_id
+		)
+		SELECT i.user_id, i.username, i.email, i.picked, card.matchups
+		FROM claimed c
+		JOIN incomplete i ON i.user_id = c.user_id
+		CROSS JOIN card;`
+	rows, err := usersDb.Query(sqlStatement, event_id, int(offset/time.Minute), now.Add(-ReminderActiveWindow), PickResultCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming reminders for event %s: %w", event_id, err)
+	}
+	defer rows.Close()
+
+	var recipients []ReminderRecipient
+	for rows.Next() {
+		var r ReminderRecipient
+		if err := rows.Scan(&r.UserID, &r.Username, &r.Email, &r.Picked, &r.Matchups); err != nil {
+			return nil, fmt.Errorf("error scanning reminder recipient row: %w", err)
+		}
+		recipients = append(recipients, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reminder recipients: %w", err)
+	}
+	return recipients, nil
+}
+
+// ReleaseReminder drops a claim whose delivery failed, so the next run tries that user again
+func ReleaseReminder(user_id int, event_id string, offset time.Duration) error {
+	sqlStatement := "DELETE FROM public.pick_reminders WHERE user_id = $1 AND event_id = $2 AND offset_minutes = $3;"
+	if _, err := usersDb.Exec(sqlStatement, user_id, event_id, int(offset/time.Minute)); err != nil {
+		return fmt.Errorf("unable to release reminder for user %d: %w", user_id, err)
+	}
+	return nil
+}
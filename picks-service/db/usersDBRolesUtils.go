@@ -0,0 +1,24 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// role_name of users allowed to override pick locks and other admin-only actions
+const AdminRoleName = "admin"
+
+// IsAdmin reports whether the user's role is admin
+func IsAdmin(userID int) (bool, error) {
+	var roleName string
+	sqlStatement := "SELECT r.role_name FROM public.users u JOIN public.roles r ON r.role_id = u.role_id WHERE u.user_id = $1;"
+	err := usersDb.QueryRow(sqlStatement, userID).Scan(&roleName)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error retrieving role for user %d: %w", userID, err)
+	}
+	return strings.EqualFold(roleName, AdminRoleName), nil
+}
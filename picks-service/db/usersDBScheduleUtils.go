@@ -0,0 +1,238 @@
+// usersDBScheduleUtils keeps track of when each matchup starts so picks can be locked
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrPickLocked = errors.New("picks are locked for this matchup")
+
+// card sections as reported by the event scraper's CardType
+const (
+	CardTypeMainCard     = "Main Card"
+	CardTypePrelims      = "Prelims"
+	CardTypeEarlyPrelims = "Early Prelims"
+)
+
+// LockMode decides which start time locks a matchup
+type LockMode string
+
+const (
+	// LockAtCardStart locks every fight once its card section (main card, prelims, early prelims) starts
+	LockAtCardStart LockMode = "card"
+	// LockAtFightStart locks each fight individually when the live feed says it started,
+	// falling back to the card section start when that isn't known
+	LockAtFightStart LockMode = "fight"
+)
+
+// ParseLockMode reads a PICK_LOCK_MODE value, rejecting anything but "card" and "fight"
+func ParseLockMode(value string) (LockMode, error) {
+	switch mode := LockMode(value); mode {
+	case LockAtCardStart, LockAtFightStart:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown pick lock mode %q: use %q or %q", value, LockAtCardStart, LockAtFightStart)
+}
+
+type MatchupSchedule struct {
+	MatchupID       string     `json:"matchup_id"`
+	EventID         string     `json:"event_id"`
+	CardType        string     `json:"card_type"`
+	WeightClass     string     `json:"weight_class,omitempty"`
+	EventName       string     `json:"event_name,omitempty"`
+	Fighter1ID      string     `json:"fighter1_id,omitempty"`
+	Fighter1Name    string     `json:"fighter1_name,omitempty"`
+	Fighter2ID      string     `json:"fighter2_id,omitempty"`
+	Fighter2Name    string     `json:"fighter2_name,omitempty"`
+	CardStartsAt    *time.Time `json:"card_starts_at"`
+	FightStartsAt   *time.Time `json:"fight_starts_at"`
+	ScheduledRounds int        `json:"scheduled_rounds,omitempty"`
+}
+
+// LockTime returns when picks for the matchup lock under mode, or false if no start time is known
+func (s MatchupSchedule) LockTime(mode LockMode) (time.Time, bool) {
+	switch {
+	case mode == LockAtFightStart && s.FightStartsAt != nil:
+		return *s.FightStartsAt, true
+	case s.CardStartsAt != nil && s.FightStartsAt != nil && s.FightStartsAt.Before(*s.CardStartsAt):
+		return *s.FightStartsAt, true
+	case s.CardStartsAt != nil:
+		return *s.CardStartsAt, true
+	case s.FightStartsAt != nil:
+		return *s.FightStartsAt, true
+	}
+	return time.Time{}, false
+}
+
+// UpsertMatchupSchedules stores the start times for a batch of matchups in one transaction
+func UpsertMatchupSchedules(schedules []MatchupSchedule) error {
+	tx, err := usersDb.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("unable to start schedule transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sqlUpsert := `INSERT INTO public.matchup_schedule (matchup_id, event_id, card_type, card_starts_at, fight_starts_at, scheduled_rounds, weight_class,
+			event_name, fighter1_id, fighter1_name, fighter2_id, fighter2_name)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), NULLIF($12, ''))
+		ON CONFLICT (matchup_id) DO UPDATE SET
+			event_id = EXCLUDED.event_id,
+			card_type = EXCLUDED.card_type,
+			weight_class = COALESCE(EXCLUDED.weight_class, matchup_schedule.weight_class),
+			event_name = COALESCE(EXCLUDED.event_name, matchup_schedule.event_name),
+			fighter1_id = COALESCE(EXCLUDED.fighter1_id, matchup_schedule.fighter1_id),
+			fighter1_name = COALESCE(EXCLUDED.fighter1_name, matchup_schedule.fighter1_name),
+			fighter2_id = COALESCE(EXCLUDED.fighter2_id, matchup_schedule.fighter2_id),
+			fighter2_name = COALESCE(EXCLUDED.fighter2_name, matchup_schedule.fighter2_name),
+			card_starts_at = COALESCE(EXCLUDED.card_starts_at, matchup_schedule.card_starts_at),
+			fight_starts_at = COALESCE(EXCLUDED.fight_starts_at, matchup_schedule.fight_starts_at),
+			scheduled_rounds = COALESCE(EXCLUDED.scheduled_rounds, matchup_schedule.scheduled_rounds),
+			updated_at = CURRENT_TIMESTAMP;`
+	for _, s := range schedules {
+		_, err := tx.ExecContext(context.Background(), sqlUpsert, s.MatchupID, s.EventID, s.CardType, s.CardStartsAt, s.FightStartsAt, s.ScheduledRounds, s.WeightClass,
+			s.EventName, s.Fighter1ID, s.Fighter1Name, s.Fighter2ID, s.Fighter2Name)
+		if err != nil {
+			return fmt.Errorf("unable to store schedule for matchup %s: %w", s.MatchupID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit schedules: %w", err)
+	}
+	return nil
+}
+
+// SetEventDate stores the date of an event, which decides the season its picks count towards
+func SetEventDate(event_id string, date time.Time) error {
+	sqlStatement := `INSERT INTO public.event_dates (event_id, event_date) VALUES ($1, $2)
+		ON CONFLICT (event_id) DO UPDATE SET event_date = EXCLUDED.event_date;`
+	if _, err := usersDb.Exec(sqlStatement, event_id, date.Format("2006-01-02")); err != nil {
+		return fmt.Errorf("unable to store date for event %s: %w", event_id, err)
+	}
+	return nil
+}
+
+// GetMatchupSchedule returns the stored schedule for a matchup, or nil if none was synced yet
+func GetMatchupSchedule(matchup_id string) (*MatchupSchedule, error) {
+	return getMatchupSchedule(usersDb, matchup_id)
+}
+
+func getMatchupSchedule(ex dbExecutor, matchup_id string) (*MatchupSchedule, error) {
+	var s MatchupSchedule
+	var cardType, weightClass, eventName, fighter1ID, fighter1Name, fighter2ID, fighter2Name sql.NullString
+	var cardStartsAt, fightStartsAt sql.NullTime
+	var scheduledRounds sql.NullInt64
+	sqlStatement := `SELECT matchup_id, event_id, card_type, card_starts_at, fight_starts_at, scheduled_rounds, weight_class,
+			event_name, fighter1_id, fighter1_name, fighter2_id, fighter2_name
+		FROM public.matchup_schedule WHERE matchup_id = $1;`
+	err := ex.QueryRowContext(context.Background(), sqlStatement, matchup_id).Scan(&s.MatchupID, &s.EventID, &cardType, &cardStartsAt, &fightStartsAt, &scheduledRounds, &weightClass,
+		&eventName, &fighter1ID, &fighter1Name, &fighter2ID, &fighter2Name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving schedule for matchup %s: %w", matchup_id, err)
+	}
+
+	s.CardType = cardType.String
+	s.WeightClass = weightClass.String
+	s.EventName = eventName.String
+	s.Fighter1ID = fighter1ID.String
+	s.Fighter1Name = fighter1Name.String
+	s.Fighter2ID = fighter2ID.String
+	s.Fighter2Name = fighter2Name.String
+	s.ScheduledRounds = int(scheduledRounds.Int64)
+	if cardStartsAt.Valid {
+		s.CardStartsAt = &cardStartsAt.Time
+	}
+	if fightStartsAt.Valid {
+		s.FightStartsAt = &fightStartsAt.Time
+	}
+	return &s, nil
+}
+
+// checkPickLock returns an error wrapping ErrPickLocked if the matchup has started under mode or already has a
+// result. It reads through ex so writes can re-check the lock inside their own transaction right before the pick is stored
+func checkPickLock(ex dbExecutor, mode LockMode, matchup_id string, now time.Time) error {
+	var graded bool
+	sqlResult := "SELECT EXISTS (SELECT 1 FROM public.matchup_results WHERE matchup_id = $1);"
+	if err := ex.QueryRowContext(context.Background(), sqlResult, matchup_id).Scan(&graded); err != nil {
+		return fmt.Errorf("error checking result for matchup %s: %w", matchup_id, err)
+	}
+
+	schedule, err := getMatchupSchedule(ex, matchup_id)
+	if err != nil {
+		return err
+	}
+	return pickLockError(graded, schedule, mode, now)
+}
+
+// pickLockError decides the lock from whether the matchup has a result and its schedule, which is nil if never synced
+func pickLockError(graded bool, schedule *MatchupSchedule, mode LockMode, now time.Time) error {
+	if graded {
+		return fmt.Errorf("%w: the fight is over", ErrPickLocked)
+	}
+	if schedule == nil {
+		return nil
+	}
+
+	lockAt, ok := schedule.LockTime(mode)
+	if ok && !now.Before(lockAt) {
+		return fmt.Errorf("%w: picks closed at %s", ErrPickLocked, lockAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ParseEventDate parses an event date as scraped ("2024-04-13" or "April 13, 2024") as midnight US Eastern time
+func ParseEventDate(eventDate string) (time.Time, error) {
+	eastern, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to load eastern time zone: %w", err)
+	}
+
+	var date time.Time
+	eventDate = strings.TrimSpace(eventDate)
+	for _, layout := range []string{"2006-01-02", "January 2, 2006", "Jan 2, 2006", time.RFC3339} {
+		if date, err = time.ParseInLocation(layout, eventDate, eastern); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised event date %q", eventDate)
+}
+
+// ParseCardStartTime combines an event date ("2024-04-13" or "April 13, 2024") with a card start time
+// as scraped from ESPN ("10:00 PM", "Sat 10:00 PM ET"), which is always US Eastern time
+func ParseCardStartTime(eventDate string, cardTime string) (time.Time, error) {
+	date, err := ParseEventDate(eventDate)
+	if err != nil {
+		return time.Time{}, err
+	}
+	eastern := date.Location()
+
+	//keep only the clock part, e.g. "Sat 10:00 PM ET" -> "10:00 PM"
+	fields := strings.Fields(strings.ToUpper(cardTime))
+	var clock []string
+	for _, f := range fields {
+		if strings.ContainsAny(f, "0123456789") || f == "AM" || f == "PM" {
+			clock = append(clock, f)
+		}
+	}
+	clockStr := strings.Join(clock, " ")
+
+	var tod time.Time
+	for _, layout := range []string{"3:04 PM", "3:04PM", "3 PM", "3PM", "15:04"} {
+		if tod, err = time.Parse(layout, clockStr); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unrecognised card start time %q", cardTime)
+	}
+
+	return time.Date(date.Year(), date.Month(), date.Day(), tod.Hour(), tod.Minute(), 0, 0, eastern), nil
+}
@@ -0,0 +1,251 @@
+// usersDBSeasonsUtils manages seasons: date ranges that picks fall into by event date, and their archived standings
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// season statuses
+const (
+	SeasonActive = "active"
+	SeasonClosed = "closed"
+)
+
+const seasonDateLayout = "2006-01-02"
+
+const MaxSeasonNameLength = 100
+
+var (
+	ErrSeasonNotFound   = errors.New("season not found")
+	ErrSeasonClosed     = errors.New("season is already closed")
+	ErrSeasonOverlap    = errors.New("season dates overlap another season")
+	ErrInvalidSeasonArg = errors.New("invalid season request")
+)
+
+type Season struct {
+	SeasonID int        `json:"season_id"`
+	Name     string     `json:"name"`
+	StartsOn string     `json:"starts_on"`
+	EndsOn   string     `json:"ends_on"`
+	Status   string     `json:"status"`
+	ClosedAt *time.Time `json:"closed_at"`
+}
+
+// SeasonChampion is a user ranked first in a closed season's archived standings
+type SeasonChampion struct {
+	Season       Season  `json:"season"`
+	UserID       int     `json:"user_id"`
+	Username     string  `json:"username"`
+	CorrectPicks int     `json:"correct_picks"`
+	TotalPicks   int     `json:"total_picks"`
+	Accuracy     float64 `json:"accuracy"`
+}
+
+const seasonColumns = "season_id, name, starts_on, ends_on, status, closed_at"
+
+func scanSeason(row interface{ Scan(...interface{}) error }) (Season, error) {
+	var s Season
+	var startsOn, endsOn time.Time
+	if err := row.Scan(&s.SeasonID, &s.Name, &startsOn, &endsOn, &s.Status, &s.ClosedAt); err != nil {
+		return s, err
+	}
+	s.StartsOn = startsOn.Format(seasonDateLayout)
+	s.EndsOn = endsOn.Format(seasonDateLayout)
+	return s, nil
+}
+
+// CreateSeason adds a season covering events dated starts_on through ends_on (YYYY-MM-DD, inclusive).
+// Seasons can't overlap, so every event belongs to at most one
+func CreateSeason(name string, starts_on string, ends_on string) (Season, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > MaxSeasonNameLength {
+		return Season{}, fmt.Errorf("%w: name must be between 1 and %d characters", ErrInvalidSeasonArg, MaxSeasonNameLength)
+	}
+
+	start, err := time.Parse(seasonDateLayout, starts_on)
+	if err != nil {
+		return Season{}, fmt.Errorf("%w: startsOn must be a YYYY-MM-DD date", ErrInvalidSeasonArg)
+	}
+	end, err := time.Parse(seasonDateLayout, ends_on)
+	if err != nil {
+		return Season{}, fmt.Errorf("%w: endsOn must be a YYYY-MM-DD date", ErrInvalidSeasonArg)
+	}
+	if end.Before(start) {
+		return Season{}, fmt.Errorf("%w: endsOn is before startsOn", ErrInvalidSeasonArg)
+	}
+
+	tx, err := usersDb.BeginTx(context.Background(), nil)
+	if err != nil {
+		return Season{}, fmt.Errorf("unable to start season transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	//serialize season creation so two overlapping seasons can't both pass the check
+	if _, err := tx.Exec("LOCK TABLE public.seasons IN SHARE ROW EXCLUSIVE MODE;"); err != nil {
+		return Season{}, fmt.Errorf("unable to lock seasons: %w", err)
+	}
+
+	var overlap bool
+	sqlOverlap := "SELECT EXISTS (SELECT 1 FROM public.seasons WHERE starts_on <= $2 AND ends_on >= $1);"
+	if err := tx.QueryRow(sqlOverlap, starts_on, ends_on).Scan(&overlap); err != nil {
+		return Season{}, fmt.Errorf("error checking season overlap: %w", err)
+	}
+	if overlap {
+		return Season{}, ErrSeasonOverlap
+	}
+
+	sqlInsert := "INSERT INTO public.seasons (name, starts_on, ends_on, status) VALUES ($1, $2, $3, $4) RETURNING " + seasonColumns + ";"
+	season, err := scanSeason(tx.QueryRow(sqlInsert, name, starts_on, ends_on, SeasonActive))
+	if err != nil {
+		return Season{}, fmt.Errorf("unable to create season: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Season{}, fmt.Errorf("unable to commit season: %w", err)
+	}
+	return season, nil
+}
+
+// GetSeason returns a season by id
+func GetSeason(seasonID int) (Season, error) {
+	season, err := scanSeason(usersDb.QueryRow("SELECT "+seasonColumns+" FROM public.seasons WHERE season_id = $1;", seasonID))
+	if err == sql.ErrNoRows {
+		return season, ErrSeasonNotFound
+	}
+	if err != nil {
+		return season, fmt.Errorf("error retrieving season %d: %w", seasonID, err)
+	}
+	return season, nil
+}
+
+// GetCurrentSeason returns the season covering today, or else the most recent one that has started
+func GetCurrentSeason() (Season, error) {
+	sqlStatement := "SELECT " + seasonColumns + ` FROM public.seasons
+		WHERE starts_on <= CURRENT_DATE
+		ORDER BY (ends_on >= CURRENT_DATE) DESC, starts_on DESC
+		LIMIT 1;`
+	season, err := scanSeason(usersDb.QueryRow(sqlStatement))
+	if err == sql.ErrNoRows {
+		return season, ErrSeasonNotFound
+	}
+	if err != nil {
+		return season, fmt.Errorf("error retrieving current season: %w", err)
+	}
+	return season, nil
+}
+
+// GetSeasons lists every season, newest first
+func GetSeasons() ([]Season, error) {
+	rows, err := usersDb.Query("SELECT " + seasonColumns + " FROM public.seasons ORDER BY starts_on DESC;")
+	if err != nil {
+		return nil, fmt.Errorf("error querying seasons: %w", err)
+	}
+	defer rows.Close()
+
+	seasons := []Season{}
+	for rows.Next() {
+		season, err := scanSeason(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning season row: %w", err)
+		}
+		seasons = append(seasons, season)
+	}
+	return seasons, rows.Err()
+}
+
+// CloseSeason freezes a season's standings into season_standings. From then on the season leaderboard
+// is served from the archive, so regrading old fights or renamed users no longer change it
+func CloseSeason(seasonID int) (Season, error) {
+	tx, err := usersDb.BeginTx(context.Background(), nil)
+	if err != nil {
+		return Season{}, fmt.Errorf("unable to start season transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	season, err := scanSeason(tx.QueryRow("SELECT "+seasonColumns+" FROM public.seasons WHERE season_id = $1 FOR UPDATE;", seasonID))
+	if err == sql.ErrNoRows {
+		return season, ErrSeasonNotFound
+	}
+	if err != nil {
+		return season, fmt.Errorf("error retrieving season %d: %w", seasonID, err)
+	}
+	if season.Status == SeasonClosed {
+		return season, ErrSeasonClosed
+	}
+
+	standings, args := liveSeasonStandingsSQL(season, []interface{}{PickResultCorrect, PickResultIncorrect})
+	args = append(args, season.SeasonID)
+	sqlArchive := fmt.Sprintf(`%s
+		INSERT INTO public.season_standings (season_id, user_id, rank, username, correct, total)
+		SELECT $%d, user_id, rank, username, correct, total FROM ranked;`, rankedSQL(standings), len(args))
+	if _, err := tx.Exec(sqlArchive, args...); err != nil {
+		return season, fmt.Errorf("unable to archive standings for season %d: %w", seasonID, err)
+	}
+
+	sqlClose := "UPDATE public.seasons SET status = $2, closed_at = CURRENT_TIMESTAMP WHERE season_id = $1 RETURNING " + seasonColumns + ";"
+	season, err = scanSeason(tx.QueryRow(sqlClose, seasonID, SeasonClosed))
+	if err != nil {
+		return season, fmt.Errorf("unable to close season %d: %w", seasonID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return season, fmt.Errorf("unable to commit season close: %w", err)
+	}
+	return season, nil
+}
+
+// GetSeasonChampions returns the winners of every closed season, newest first. Users tied for first
+// are all champions
+func GetSeasonChampions() ([]SeasonChampion, error) {
+	sqlStatement := `SELECT s.season_id, s.name, s.starts_on, s.ends_on, s.status, s.closed_at,
+			ss.user_id, ss.username, ss.correct, ss.total
+		FROM public.seasons s
+		JOIN public.season_standings ss ON ss.season_id = s.season_id AND ss.rank = 1
+		WHERE s.status = $1
+		ORDER BY s.starts_on DESC, ss.username;`
+	rows, err := usersDb.Query(sqlStatement, SeasonClosed)
+	if err != nil {
+		return nil, fmt.Errorf("error querying season champions: %w", err)
+	}
+	defer rows.Close()
+
+	champions := []SeasonChampion{}
+	for rows.Next() {
+		var c SeasonChampion
+		var startsOn, endsOn time.Time
+		err := rows.Scan(&c.Season.SeasonID, &c.Season.Name, &startsOn, &endsOn, &c.Season.Status, &c.Season.ClosedAt,
+			&c.UserID, &c.Username, &c.CorrectPicks, &c.TotalPicks)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning season champion row: %w", err)
+		}
+		c.Season.StartsOn = startsOn.Format(seasonDateLayout)
+		c.Season.EndsOn = endsOn.Format(seasonDateLayout)
+		c.Accuracy = accuracy(c.CorrectPicks, c.TotalPicks)
+		champions = append(champions, c)
+	}
+	return champions, rows.Err()
+}
+
+// liveSeasonStandingsSQL computes (user_id, correct, total) over graded picks whose event falls in the
+// season. Events without a synced date fall back to the day their fight was graded. args must already
+// hold the correct and incorrect results as $1 and $2
+func liveSeasonStandingsSQL(season Season, args []interface{}) (string, []interface{}) {
+	args = append(args, season.StartsOn, season.EndsOn)
+	return fmt.Sprintf(`SELECT p.user_id, COUNT(*) FILTER (WHERE p.pick_result = $1) AS correct, COUNT(*) AS total
+		FROM public.picks p
+		JOIN public.matchup_results mr ON mr.matchup_id = p.matchup_id
+		LEFT JOIN public.event_dates ed ON ed.event_id = p.event_id
+		WHERE p.pick_result IN ($1, $2)
+			AND COALESCE(ed.event_date, mr.graded_at::date) BETWEEN $%d::date AND $%d::date
+		GROUP BY p.user_id`, len(args)-1, len(args)), args
+}
+
+// archivedSeasonStandingsSQL reads (user_id, correct, total) back from a closed season's archive
+func archivedSeasonStandingsSQL(season Season) (string, []interface{}) {
+	return `SELECT user_id, correct, total FROM public.season_standings WHERE season_id = $1`, []interface{}{season.SeasonID}
+}
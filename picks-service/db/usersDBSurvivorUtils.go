@@ -0,0 +1,366 @@
+// usersDBSurvivorUtils runs survivor contests: one unused fighter per event, a wrong pick eliminates the entry
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// survivor entry statuses
+const (
+	SurvivorAlive      = "alive"
+	SurvivorEliminated = "eliminated"
+)
+
+const MaxSurvivorNameLength = 100
+
+var (
+	ErrSurvivorNotFound      = errors.New("survivor contest not found")
+	ErrSurvivorNotEntered    = errors.New("not entered in this survivor contest")
+	ErrSurvivorEliminated    = errors.New("entry has been eliminated")
+	ErrSurvivorStarted       = errors.New("survivor contest has already started")
+	ErrSurvivorFighterUsed   = errors.New("fighter already used in this survivor contest")
+	ErrInvalidSurvivorArg    = errors.New("invalid survivor request")
+	ErrSurvivorPickLocked    = errors.New("survivor pick for this event is locked")
+	ErrSurvivorAlreadyJoined = errors.New("already entered in this survivor contest")
+)
+
+type SurvivorContest struct {
+	ContestID int    `json:"contest_id"`
+	Name      string `json:"name"`
+	CreatedBy int    `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+	Entries   int    `json:"entries"`
+	Alive     int    `json:"alive"`
+}
+
+type SurvivorPick struct {
+	ContestID int    `json:"contest_id"`
+	UserID    int    `json:"user_id"`
+	EventID   string `json:"event_id"`
+	MatchupID string `json:"matchup_id"`
+	FighterID string `json:"fighter_id"`
+	Result    string `json:"result"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type SurvivorStanding struct {
+	Rank              int     `json:"rank"`
+	UserID            int     `json:"user_id"`
+	Username          string  `json:"username"`
+	Status            string  `json:"status"`
+	EventsSurvived    int     `json:"events_survived"`
+	EliminatedEventID *string `json:"eliminated_event_id"`
+}
+
+type SurvivorStandings struct {
+	Contest   SurvivorContest    `json:"contest"`
+	Standings []SurvivorStanding `json:"standings"`
+}
+
+// CreateSurvivorContest creates a contest with the creator as its first entry
+func CreateSurvivorContest(name string, userID int) (SurvivorContest, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > MaxSurvivorNameLength {
+		return SurvivorContest{}, fmt.Errorf("%w: name must be between 1 and %d characters", ErrInvalidSurvivorArg, MaxSurvivorNameLength)
+	}
+
+	tx, err := usersDb.BeginTx(context.Background(), nil)
+	if err != nil {
+		return SurvivorContest{}, fmt.Errorf("unable to start survivor transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	contest := SurvivorContest{Name: name, CreatedBy: userID, Entries: 1, Alive: 1}
+	sqlContest := "INSERT INTO public.survivor_contests (name, created_by) VALUES ($1, $2) RETURNING contest_id, created_at;"
+	if err := tx.QueryRow(sqlContest, name, userID).Scan(&contest.ContestID, &contest.CreatedAt); err != nil {
+		return SurvivorContest{}, fmt.Errorf("unable to create survivor contest: %w", err)
+	}
+
+	sqlEntry := "INSERT INTO public.survivor_entries (contest_id, user_id, status) VALUES ($1, $2, $3);"
+	if _, err := tx.Exec(sqlEntry, contest.ContestID, userID, SurvivorAlive); err != nil {
+		return SurvivorContest{}, fmt.Errorf("unable to enter creator in survivor contest: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SurvivorContest{}, fmt.Errorf("unable to commit survivor contest: %w", err)
+	}
+	return contest, nil
+}
+
+// GetSurvivorContest returns a contest with its entry counts
+func GetSurvivorContest(contestID int) (SurvivorContest, error) {
+	var c SurvivorContest
+	sqlStatement := `SELECT c.contest_id, c.name, c.created_by, c.created_at,
+			COUNT(e.user_id), COUNT(e.user_id) FILTER (WHERE e.status = $2)
+		FROM public.survivor_contests c
+		LEFT JOIN public.survivor_entries e ON e.contest_id = c.contest_id
+		WHERE c.contest_id = $1
+		GROUP BY c.contest_id;`
+	err := usersDb.QueryRow(sqlStatement, contestID, SurvivorAlive).Scan(&c.ContestID, &c.Name, &c.CreatedBy, &c.CreatedAt, &c.Entries, &c.Alive)
+	if err == sql.ErrNoRows {
+		return c, ErrSurvivorNotFound
+	}
+	if err != nil {
+		return c, fmt.Errorf("error retrieving survivor contest %d: %w", contestID, err)
+	}
+	return c, nil
+}
+
+// JoinSurvivorContest enters a user. Entries close once the first survivor pick of the contest is graded
+func JoinSurvivorContest(contestID int, userID int) error {
+	if _, err := GetSurvivorContest(contestID); err != nil {
+		return err
+	}
+
+	var started bool
+	sqlStarted := "SELECT EXISTS (SELECT 1 FROM public.survivor_picks WHERE contest_id = $1 AND result <> $2);"
+	if err := usersDb.QueryRow(sqlStarted, contestID, PickResultPending).Scan(&started); err != nil {
+		return fmt.Errorf("error checking start of survivor contest %d: %w", contestID, err)
+	}
+	if started {
+		return ErrSurvivorStarted
+	}
+
+	sqlJoin := `INSERT INTO public.survivor_entries (contest_id, user_id, status) VALUES ($1, $2, $3)
+		ON CONFLICT (contest_id, user_id) DO NOTHING;`
+	res, err := usersDb.Exec(sqlJoin, contestID, userID, SurvivorAlive)
+	if err != nil {
+		return fmt.Errorf("unable to join survivor contest %d: %w", contestID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSurvivorAlreadyJoined
+	}
+	return nil
+}
+
+// SubmitSurvivorPick sets the entry's pick for an event. It can be changed until the picked fight locks under the
+// store's lock mode, the matchup must be on the event's synced card and the fighter can't have been used for another event
+func (s *PostgresPickStore) SubmitSurvivorPick(contestID int, userID int, event_id string, matchup_id string, fighter_id string) (SurvivorPick, error) {
+	pick := SurvivorPick{ContestID: contestID, UserID: userID, EventID: event_id, MatchupID: matchup_id, FighterID: fighter_id, Result: PickResultPending}
+	if event_id == "" || matchup_id == "" || fighter_id == "" {
+		return pick, fmt.Errorf("%w: eventId, matchupId and fighterId are required", ErrInvalidSurvivorArg)
+	}
+
+	cardMatchupIds, err := getEventMatchupIds(s.db, event_id)
+	if err != nil {
+		return pick, err
+	}
+	onCard := false
+	for _, id := range cardMatchupIds {
+		if id == matchup_id {
+			onCard = true
+			break
+		}
+	}
+	if !onCard {
+		return pick, fmt.Errorf("%w: matchup %s is not on the card for event %s", ErrInvalidSurvivorArg, matchup_id, event_id)
+	}
+	if err := validatePickWith(s.events, event_id, matchup_id, fighter_id); err != nil {
+		return pick, err
+	}
+
+	if err := checkPickLock(s.db, s.lockMode, matchup_id, time.Now()); err != nil {
+		return pick, err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return pick, fmt.Errorf("unable to start survivor pick transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	//lock the entry so two picks for the same entry can't race past the used fighter check
+	var status string
+	sqlEntry := "SELECT status FROM public.survivor_entries WHERE contest_id = $1 AND user_id = $2 FOR UPDATE;"
+	err = tx.QueryRowContext(context.Background(), sqlEntry, contestID, userID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return pick, ErrSurvivorNotEntered
+	}
+	if err != nil {
+		return pick, fmt.Errorf("error retrieving survivor entry: %w", err)
+	}
+	if status != SurvivorAlive {
+		return pick, ErrSurvivorEliminated
+	}
+
+	//an existing pick can only be replaced while its own fight is still open
+	var currentMatchup, currentResult string
+	sqlCurrent := "SELECT matchup_id, result FROM public.survivor_picks WHERE contest_id = $1 AND user_id = $2 AND event_id = $3;"
+	err = tx.QueryRowContext(context.Background(), sqlCurrent, contestID, userID, event_id).Scan(&currentMatchup, &currentResult)
+	if err != nil && err != sql.ErrNoRows {
+		return pick, fmt.Errorf("error retrieving survivor pick: %w", err)
+	}
+	if err == nil && currentResult != PickResultCancelled && currentMatchup != matchup_id {
+		if lockErr := checkPickLock(tx, s.lockMode, currentMatchup, time.Now()); lockErr != nil {
+			return pick, ErrSurvivorPickLocked
+		}
+	}
+
+	//the new fight's lock is checked again now that the entry is held
+	if err := checkPickLock(tx, s.lockMode, matchup_id, time.Now()); err != nil {
+		return pick, err
+	}
+
+	var used bool
+	sqlUsed := `SELECT EXISTS (SELECT 1 FROM public.survivor_picks
+		WHERE contest_id = $1 AND user_id = $2 AND fighter_id = $3 AND event_id <> $4 AND result <> $5);`
+	if err := tx.QueryRowContext(context.Background(), sqlUsed, contestID, userID, fighter_id, event_id, PickResultCancelled).Scan(&used); err != nil {
+		return pick, fmt.Errorf("error checking used fighters: %w", err)
+	}
+	if used {
+		return pick, ErrSurvivorFighterUsed
+	}
+
+	sqlUpsert := `INSERT INTO public.survivor_picks (contest_id, user_id, event_id, matchup_id, fighter_id, result)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (contest_id, user_id, event_id) DO UPDATE SET
+			matchup_id = EXCLUDED.matchup_id,
+			fighter_id = EXCLUDED.fighter_id,
+			result = EXCLUDED.result,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at;`
+	err = tx.QueryRowContext(context.Background(), sqlUpsert, contestID, userID, event_id, matchup_id, fighter_id, PickResultPending).Scan(&pick.UpdatedAt)
+	if err != nil {
+		return pick, fmt.Errorf("unable to save survivor pick: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return pick, fmt.Errorf("unable to commit survivor pick: %w", err)
+	}
+	return pick, nil
+}
+
+// GetSurvivorPicks returns a page of a user's picks in a contest, oldest event first
+func GetSurvivorPicks(contestID int, userID int, q ListQuery) (Page[SurvivorPick], error) {
+	limit, offset, err := q.normalize(listingSurvivorPicks, DefaultPickPageSize, MaxPickPageSize)
+	if err != nil {
+		return Page[SurvivorPick]{}, err
+	}
+
+	var total int
+	sqlCount := "SELECT COUNT(*) FROM public.survivor_picks WHERE contest_id = $1 AND user_id = $2;"
+	if err := usersDb.QueryRow(sqlCount, contestID, userID).Scan(&total); err != nil {
+		return Page[SurvivorPick]{}, fmt.Errorf("error counting survivor picks: %w", err)
+	}
+
+	sqlStatement := `SELECT contest_id, user_id, event_id, matchup_id, fighter_id, result, updated_at
+		FROM public.survivor_picks WHERE contest_id = $1 AND user_id = $2 ORDER BY created_at, event_id
+		LIMIT $3 OFFSET $4;`
+	rows, err := usersDb.Query(sqlStatement, contestID, userID, limit, offset)
+	if err != nil {
+		return Page[SurvivorPick]{}, fmt.Errorf("error querying survivor picks: %w", err)
+	}
+	defer rows.Close()
+
+	var picks []SurvivorPick
+	for rows.Next() {
+		var p SurvivorPick
+		if err := rows.Scan(&p.ContestID, &p.UserID, &p.EventID, &p.MatchupID, &p.FighterID, &p.Result, &p.UpdatedAt); err != nil {
+			return Page[SurvivorPick]{}, fmt.Errorf("error scanning survivor pick row: %w", err)
+		}
+		picks = append(picks, p)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[SurvivorPick]{}, fmt.Errorf("error iterating survivor pick rows: %w", err)
+	}
+	return finishListPage(listingSurvivorPicks, picks, offset, total), nil
+}
+
+// GetSurvivorStandings ranks entries: survivors first, then by how many events each entry got through
+func GetSurvivorStandings(contestID int) (SurvivorStandings, error) {
+	contest, err := GetSurvivorContest(contestID)
+	if err != nil {
+		return SurvivorStandings{}, err
+	}
+
+	sqlStatement := `SELECT RANK() OVER (ORDER BY e.status = $2 DESC, COUNT(sp.event_id) DESC),
+			e.user_id, u.username, e.status, COUNT(sp.event_id), e.eliminated_event_id
+		FROM public.survivor_entries e
+		JOIN public.users u ON u.user_id = e.user_id
+		LEFT JOIN public.survivor_picks sp ON sp.contest_id = e.contest_id AND sp.user_id = e.user_id
+			AND sp.result IN ($3, $4, $5)
+		WHERE e.contest_id = $1
+		GROUP BY e.user_id, u.username, e.status, e.eliminated_event_id
+		ORDER BY 1, u.username;`
+	rows, err := usersDb.Query(sqlStatement, contestID, SurvivorAlive, PickResultCorrect, PickResultPush, PickResultVoid)
+	if err != nil {
+		return SurvivorStandings{}, fmt.Errorf("error querying survivor standings: %w", err)
+	}
+	defer rows.Close()
+
+	standings := SurvivorStandings{Contest: contest, Standings: []SurvivorStanding{}}
+	for rows.Next() {
+		var s SurvivorStanding
+		if err := rows.Scan(&s.Rank, &s.UserID, &s.Username, &s.Status, &s.EventsSurvived, &s.EliminatedEventID); err != nil {
+			return SurvivorStandings{}, fmt.Errorf("error scanning survivor standing row: %w", err)
+		}
+		standings.Standings = append(standings.Standings, s)
+	}
+	return standings, rows.Err()
+}
+
+// gradeSurvivorPicks runs inside the grading transaction with the same rules as gradePicks: the winner's
+// pickers survive, the others are eliminated, and a bout with no winner (noWinnerResult) lets everyone
+// through. Entries touched by the matchup then have their status recomputed from all of their picks,
+// so regrading a result also revives entries it had wrongly eliminated. An entry is eliminated at its
+// earliest losing event by event date (pick time when the date isn't known), not the order results came in
+func gradeSurvivorPicks(tx *sql.Tx, matchup_id string, event_id string, winning_fighter_id string, noWinnerResult string) error {
+	sqlGrade := `UPDATE public.survivor_picks SET
+			result = CASE
+				WHEN $1 = '' THEN $4
+				WHEN fighter_id = $1 THEN $5
+				ELSE $6
+			END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE matchup_id = $2 AND event_id = $3 AND result <> $7;`
+	_, err := tx.ExecContext(context.Background(), sqlGrade, winning_fighter_id, matchup_id, event_id,
+		noWinnerResult, PickResultCorrect, PickResultIncorrect, PickResultCancelled)
+	if err != nil {
+		return fmt.Errorf("unable to grade survivor picks for matchup %s: %w", matchup_id, err)
+	}
+
+	sqlStatus := `UPDATE public.survivor_entries e SET
+			status = CASE WHEN x.eliminated_event_id IS NULL THEN $3 ELSE $4 END,
+			eliminated_event_id = x.eliminated_event_id
+		FROM (
+			SELECT sp.contest_id, sp.user_id,
+				(SELECT i.event_id FROM public.survivor_picks i
+					LEFT JOIN public.event_dates ed ON ed.event_id = i.event_id
+					WHERE i.contest_id = sp.contest_id AND i.user_id = sp.user_id AND i.result = $5
+					ORDER BY ed.event_date NULLS LAST, i.created_at LIMIT 1) AS eliminated_event_id
+			FROM public.survivor_picks sp
+			WHERE sp.matchup_id = $1 AND sp.event_id = $2
+		) x
+		WHERE e.contest_id = x.contest_id AND e.user_id = x.user_id;`
+	_, err = tx.ExecContext(context.Background(), sqlStatus, matchup_id, event_id, SurvivorAlive, SurvivorEliminated, PickResultIncorrect)
+	if err != nil {
+		return fmt.Errorf("unable to update survivor entries for matchup %s: %w", matchup_id, err)
+	}
+	return nil
+}
+
+// rebookSurvivorPicks cancels survivor picks on a withdrawn fighter, freeing the fighter and letting the
+// entry pick again, and moves the rest to the rebooked matchup id
+func rebookSurvivorPicks(tx *sql.Tx, event_id string, matchup_id string, new_matchup_id string, withdrawn_fighter_id string) error {
+	sqlCancel := `UPDATE public.survivor_picks SET result = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE matchup_id = $1 AND event_id = $2 AND fighter_id = $3 AND result = $5;`
+	_, err := tx.ExecContext(context.Background(), sqlCancel, matchup_id, event_id, withdrawn_fighter_id, PickResultCancelled, PickResultPending)
+	if err != nil {
+		return fmt.Errorf("unable to cancel survivor picks for matchup %s: %w", matchup_id, err)
+	}
+
+	if new_matchup_id == matchup_id {
+		return nil
+	}
+	sqlMove := `UPDATE public.survivor_picks SET matchup_id = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE matchup_id = $1 AND event_id = $2 AND result = $4;`
+	if _, err := tx.ExecContext(context.Background(), sqlMove, matchup_id, event_id, new_matchup_id, PickResultPending); err != nil {
+		return fmt.Errorf("unable to migrate survivor picks for matchup %s: %w", matchup_id, err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+// usersDBValidationUtils checks picks against the real event card before they are stored
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"picks-service/events"
+)
+
+var ErrInvalidPick = errors.New("invalid pick")
+
+// validatePickWith checks that the matchup is on the event's card in source and that the selection fights in it.
+// Problems with the pick wrap ErrInvalidPick; any other error means the card couldn't be looked up
+func validatePickWith(source events.Source, event_id string, matchup_id string, selection_fighter_id string) error {
+	event, err := lookupEvent(source, event_id)
+	if err != nil || event == nil {
+		return err
+	}
+	return checkPickOnCard(*event, matchup_id, selection_fighter_id)
+}
+
+// validateMatchupWith checks that the matchup is on the event's card, for props which have no selected fighter
+func validateMatchupWith(source events.Source, event_id string, matchup_id string) error {
+	event, err := lookupEvent(source, event_id)
+	if err != nil || event == nil {
+		return err
+	}
+	if _, ok := event.Matchup(matchup_id); !ok {
+		return fmt.Errorf("%w: matchup %s is not part of event %s", ErrInvalidPick, matchup_id, event.EventID)
+	}
+	return nil
+}
+
+// lookupEvent fetches an event card, or returns nil when there is no source to validate with
+func lookupEvent(source events.Source, event_id string) (*events.Event, error) {
+	if source == nil {
+		return nil, nil
+	}
+
+	event, err := source.GetEvent(context.Background(), event_id)
+	if errors.Is(err, events.ErrEventNotFound) {
+		return nil, fmt.Errorf("%w: event %s does not exist", ErrInvalidPick, event_id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to look up event %s: %w", event_id, err)
+	}
+	return &event, nil
+}
+
+// checkPickOnCard validates a pick against an already fetched card, so a batch only fetches it once
+func checkPickOnCard(event events.Event, matchup_id string, selection_fighter_id string) error {
+	matchup, ok := event.Matchup(matchup_id)
+	if !ok {
+		return fmt.Errorf("%w: matchup %s is not part of event %s", ErrInvalidPick, matchup_id, event.EventID)
+	}
+	if !matchup.HasFighter(selection_fighter_id) {
+		return fmt.Errorf("%w: fighter %s is not in matchup %s", ErrInvalidPick, selection_fighter_id, matchup_id)
+	}
+	return nil
+}
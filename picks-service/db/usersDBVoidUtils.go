@@ -0,0 +1,220 @@
+// usersDBVoidUtils handles bouts that end without a winner, get scrapped or get a new opponent
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ErrMatchupGraded is returned when rebooking a matchup that already has a result
+var ErrMatchupGraded = errors.New("matchup already has a result")
+
+type RebookSummary struct {
+	MatchupID          string `json:"matchup_id"`
+	NewMatchupID       string `json:"new_matchup_id"`
+	EventID            string `json:"event_id"`
+	WithdrawnFighterID string `json:"withdrawn_fighter_id"`
+	Migrated           int    `json:"migrated"`
+	Cancelled          int    `json:"cancelled"`
+}
+
+// NoWinnerOutcome tells draws from no contests using the scraper's Result string, e.g. "FinalDrawR3, 5:00"
+// or "FinalNCR1, 2:11". Anything else stays the ambiguous draw_no_contest, which voids like a no contest
+func NoWinnerOutcome(result string) string {
+	compact := strings.ToUpper(strings.Join(strings.Fields(result), ""))
+	compact = strings.TrimPrefix(compact, "FINAL")
+
+	switch {
+	case strings.Contains(compact, "DRAW"):
+		return OutcomeDraw
+	case strings.HasPrefix(compact, "NC") || strings.Contains(compact, "NOCONTEST"):
+		return OutcomeNoContest
+	}
+	return OutcomeDrawNoContest
+}
+
+// CancelMatchup marks a scrapped bout as cancelled. Its picks and props are cancelled, and any counters a
+// previous grading added are taken back. The matchup stays locked like any other graded matchup
+func (s *PostgresPickStore) CancelMatchup(event_id string, matchup_id string) (GradingSummary, error) {
+	summary := GradingSummary{MatchupID: matchup_id, EventID: event_id}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return summary, fmt.Errorf("unable to start cancel transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := recordMatchupResult(tx, matchup_id, event_id, "", OutcomeCancelled, "", FightResult{}); err != nil {
+		return summary, err
+	}
+
+	if err := gradePicks(tx, matchup_id, event_id, "", PickResultCancelled, &summary); err != nil {
+		return summary, err
+	}
+
+	if err := gradeSurvivorPicks(tx, matchup_id, event_id, "", PickResultCancelled); err != nil {
+		return summary, err
+	}
+
+	sqlProps := `UPDATE public.prop_picks
+		SET method_result = $3, round_result = CASE WHEN round IS NULL THEN NULL ELSE $3 END
+		WHERE matchup_id = $1 AND event_id = $2;`
+	if _, err := tx.ExecContext(context.Background(), sqlProps, matchup_id, event_id, PickResultCancelled); err != nil {
+		return summary, fmt.Errorf("unable to cancel prop picks for matchup %s: %w", matchup_id, err)
+	}
+
+	if err := notifyEventUpdate(tx, EventUpdate{Type: UpdateResult, EventID: event_id, MatchupID: matchup_id}); err != nil {
+		return summary, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return summary, fmt.Errorf("unable to commit cancellation of matchup %s: %w", matchup_id, err)
+	}
+
+	invalidateConsensus(matchup_id, event_id)
+	log.Printf("Cancelled matchup %s: %d picks cancelled", matchup_id, summary.Cancelled)
+	return summary, nil
+}
+
+// RebookMatchup handles a replacement opponent. Picks on the withdrawn fighter (and their props) are
+// cancelled; the user can pick the new fight again. Picks on the fighter who stays move to new_matchup_id
+// when the scraper gave the new bout a new id, with odds_at_pick cleared since the line changed with the
+// opponent. Users who already picked the new matchup keep that pick and their old one is cancelled
+func (s *PostgresPickStore) RebookMatchup(event_id string, matchup_id string, new_matchup_id string, withdrawn_fighter_id string) (RebookSummary, error) {
+	if new_matchup_id == "" {
+		new_matchup_id = matchup_id
+	}
+	summary := RebookSummary{MatchupID: matchup_id, NewMatchupID: new_matchup_id, EventID: event_id, WithdrawnFighterID: withdrawn_fighter_id}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return summary, fmt.Errorf("unable to start rebook transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var graded bool
+	sqlGraded := "SELECT EXISTS (SELECT 1 FROM public.matchup_results WHERE matchup_id = $1);"
+	if err := tx.QueryRowContext(context.Background(), sqlGraded, matchup_id).Scan(&graded); err != nil {
+		return summary, fmt.Errorf("error checking result for matchup %s: %w", matchup_id, err)
+	}
+	if graded {
+		return summary, ErrMatchupGraded
+	}
+
+	//props first, while the moneyline picks they ride on are still pending on the old matchup
+	sqlCancelProps := `UPDATE public.prop_picks pp
+		SET method_result = $4, round_result = CASE WHEN pp.round IS NULL THEN NULL ELSE $4 END
+		FROM public.picks p
+		WHERE p.user_id = pp.user_id AND p.matchup_id = pp.matchup_id AND p.event_id = pp.event_id
+			AND pp.matchup_id = $1 AND pp.event_id = $2 AND p.selection_fighter_id = $3 AND p.pick_result = $5;`
+	_, err = tx.ExecContext(context.Background(), sqlCancelProps, matchup_id, event_id, withdrawn_fighter_id,
+		PickResultCancelled, PickResultPending)
+	if err != nil {
+		return summary, fmt.Errorf("unable to cancel prop picks for matchup %s: %w", matchup_id, err)
+	}
+
+	sqlCancel := `UPDATE public.picks SET pick_result = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE matchup_id = $1 AND event_id = $2 AND selection_fighter_id = $3 AND pick_result = $5
+		RETURNING pick_id, user_id, matchup_id, event_id, selection_fighter_id`
+	summary.Cancelled, err = recordPickChanges(tx, PickChangeCancel, sqlCancel, matchup_id, event_id, withdrawn_fighter_id,
+		PickResultCancelled, PickResultPending)
+	if err != nil {
+		return summary, fmt.Errorf("unable to cancel picks for matchup %s: %w", matchup_id, err)
+	}
+
+	if err := rebookSurvivorPicks(tx, event_id, matchup_id, new_matchup_id, withdrawn_fighter_id); err != nil {
+		return summary, err
+	}
+
+	if new_matchup_id != matchup_id {
+		if err := migratePicks(tx, event_id, matchup_id, new_matchup_id, &summary); err != nil {
+			return summary, err
+		}
+		if err := notifyEventUpdate(tx, EventUpdate{Type: UpdatePicks, EventID: event_id, MatchupID: new_matchup_id}); err != nil {
+			return summary, err
+		}
+	}
+	if err := notifyEventUpdate(tx, EventUpdate{Type: UpdatePicks, EventID: event_id, MatchupID: matchup_id}); err != nil {
+		return summary, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return summary, fmt.Errorf("unable to commit rebook of matchup %s: %w", matchup_id, err)
+	}
+
+	invalidateConsensus(matchup_id, event_id)
+	invalidateConsensus(new_matchup_id, event_id)
+	log.Printf("Rebooked matchup %s as %s: %d picks migrated, %d cancelled", matchup_id, new_matchup_id,
+		summary.Migrated, summary.Cancelled)
+	return summary, nil
+}
+
+// migratePicks moves the pending picks and props of a matchup to its new id, along with the lock schedule.
+// Moved and leftover cancelled picks are recorded in pick_history
+func migratePicks(tx dbExecutor, event_id string, matchup_id string, new_matchup_id string, summary *RebookSummary) error {
+	sqlProps := `UPDATE public.prop_picks pp SET matchup_id = $3
+		FROM public.picks p
+		WHERE p.user_id = pp.user_id AND p.matchup_id = pp.matchup_id AND p.event_id = pp.event_id
+			AND pp.matchup_id = $1 AND pp.event_id = $2 AND p.pick_result = $4
+			AND NOT EXISTS (SELECT 1 FROM public.picks n
+				WHERE n.user_id = p.user_id AND n.matchup_id = $3 AND n.event_id = $2)
+			AND NOT EXISTS (SELECT 1 FROM public.prop_picks n
+				WHERE n.user_id = pp.user_id AND n.matchup_id = $3 AND n.event_id = $2);`
+	_, err := tx.ExecContext(context.Background(), sqlProps, matchup_id, event_id, new_matchup_id, PickResultPending)
+	if err != nil {
+		return fmt.Errorf("unable to migrate prop picks for matchup %s: %w", matchup_id, err)
+	}
+
+	sqlMove := `UPDATE public.picks p SET matchup_id = $3, odds_at_pick = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE p.matchup_id = $1 AND p.event_id = $2 AND p.pick_result = $4
+			AND NOT EXISTS (SELECT 1 FROM public.picks n
+				WHERE n.user_id = p.user_id AND n.matchup_id = $3 AND n.event_id = $2)
+		RETURNING p.pick_id, p.user_id, p.matchup_id, p.event_id, p.selection_fighter_id`
+	summary.Migrated, err = recordPickChanges(tx, PickChangeMigrate, sqlMove, matchup_id, event_id, new_matchup_id, PickResultPending)
+	if err != nil {
+		return fmt.Errorf("unable to migrate picks for matchup %s: %w", matchup_id, err)
+	}
+
+	//whatever is still pending on the old id lost out to a pick already made on the new one
+	sqlLeftover := `UPDATE public.picks SET pick_result = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE matchup_id = $1 AND event_id = $2 AND pick_result = $4
+		RETURNING pick_id, user_id, matchup_id, event_id, selection_fighter_id`
+	leftover, err := recordPickChanges(tx, PickChangeCancel, sqlLeftover, matchup_id, event_id, PickResultCancelled, PickResultPending)
+	if err != nil {
+		return fmt.Errorf("unable to cancel leftover picks for matchup %s: %w", matchup_id, err)
+	}
+	summary.Cancelled += leftover
+
+	sqlLeftoverProps := `UPDATE public.prop_picks
+		SET method_result = $3, round_result = CASE WHEN round IS NULL THEN NULL ELSE $3 END
+		WHERE matchup_id = $1 AND event_id = $2 AND method_result = $4;`
+	_, err = tx.ExecContext(context.Background(), sqlLeftoverProps, matchup_id, event_id, PickResultCancelled, PickResultPending)
+	if err != nil {
+		return fmt.Errorf("unable to cancel leftover prop picks for matchup %s: %w", matchup_id, err)
+	}
+
+	//the new bout takes the old slot on the card until the next schedule sync says otherwise,
+	//and the old one leaves the card so full-card modes stop expecting a pick on it. the withdrawn
+	//fighter's side is left empty since the replacement is only known once the schedule syncs
+	sqlSchedule := `INSERT INTO public.matchup_schedule (matchup_id, event_id, card_type, card_starts_at, fight_starts_at, scheduled_rounds, weight_class,
+			event_name, fighter1_id, fighter1_name, fighter2_id, fighter2_name)
+		SELECT $3, event_id, card_type, card_starts_at, fight_starts_at, scheduled_rounds, weight_class, event_name,
+			CASE WHEN fighter1_id = $4 THEN NULL ELSE fighter1_id END,
+			CASE WHEN fighter1_id = $4 THEN NULL ELSE fighter1_name END,
+			CASE WHEN fighter2_id = $4 THEN NULL ELSE fighter2_id END,
+			CASE WHEN fighter2_id = $4 THEN NULL ELSE fighter2_name END
+		FROM public.matchup_schedule WHERE matchup_id = $1 AND event_id = $2
+		ON CONFLICT (matchup_id) DO NOTHING;`
+	if _, err := tx.ExecContext(context.Background(), sqlSchedule, matchup_id, event_id, new_matchup_id, summary.WithdrawnFighterID); err != nil {
+		return fmt.Errorf("unable to copy schedule of matchup %s: %w", matchup_id, err)
+	}
+
+	sqlRemove := "DELETE FROM public.matchup_schedule WHERE matchup_id = $1 AND event_id = $2;"
+	if _, err := tx.ExecContext(context.Background(), sqlRemove, matchup_id, event_id); err != nil {
+		return fmt.Errorf("unable to remove schedule of matchup %s: %w", matchup_id, err)
+	}
+	return nil
+}
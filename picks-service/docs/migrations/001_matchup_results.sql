@@ -0,0 +1,26 @@
+-- Table: public.matchup_results
+
+-- DROP TABLE IF EXISTS public.matchup_results;
+
+CREATE TABLE IF NOT EXISTS public.matchup_results
+(
+    matchup_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    event_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    winner_fighter_id character varying(32) COLLATE pg_catalog."default",
+    outcome character varying(50) COLLATE pg_catalog."default" NOT NULL,
+    graded_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT matchup_results_pkey PRIMARY KEY (matchup_id)
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.matchup_results
+    OWNER to introducing_first_users_user;
+
+-- Index: picks_matchup_event_result_idx
+
+-- DROP INDEX IF EXISTS public.picks_matchup_event_result_idx;
+
+CREATE INDEX IF NOT EXISTS picks_matchup_event_result_idx
+    ON public.picks USING btree
+    (matchup_id, event_id, pick_result);
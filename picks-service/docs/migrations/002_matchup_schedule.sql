@@ -0,0 +1,27 @@
+-- Table: public.matchup_schedule
+
+-- DROP TABLE IF EXISTS public.matchup_schedule;
+
+CREATE TABLE IF NOT EXISTS public.matchup_schedule
+(
+    matchup_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    event_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    card_type character varying(50) COLLATE pg_catalog."default",
+    card_starts_at timestamp with time zone,
+    fight_starts_at timestamp with time zone,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT matchup_schedule_pkey PRIMARY KEY (matchup_id)
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.matchup_schedule
+    OWNER to introducing_first_users_user;
+
+-- Index: matchup_schedule_event_id_idx
+
+-- DROP INDEX IF EXISTS public.matchup_schedule_event_id_idx;
+
+CREATE INDEX IF NOT EXISTS matchup_schedule_event_id_idx
+    ON public.matchup_schedule USING btree
+    (event_id);
@@ -0,0 +1,48 @@
+-- Table: public.prop_picks
+
+-- DROP TABLE IF EXISTS public.prop_picks;
+
+CREATE TABLE IF NOT EXISTS public.prop_picks
+(
+    prop_pick_id serial NOT NULL,
+    user_id integer NOT NULL,
+    matchup_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    event_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    method character varying(20) COLLATE pg_catalog."default" NOT NULL,
+    round integer,
+    method_result character varying(50) COLLATE pg_catalog."default" DEFAULT 'pending'::character varying,
+    round_result character varying(50) COLLATE pg_catalog."default",
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT prop_picks_pkey PRIMARY KEY (prop_pick_id),
+    CONSTRAINT prop_picks_user_matchup_key UNIQUE (user_id, matchup_id, event_id),
+    CONSTRAINT fk_user FOREIGN KEY (user_id)
+        REFERENCES public.users (user_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.prop_picks
+    OWNER to introducing_first_users_user;
+
+-- Trigger: set_updated_at
+
+-- DROP TRIGGER IF EXISTS set_updated_at ON public.prop_picks;
+
+CREATE OR REPLACE TRIGGER set_updated_at
+    BEFORE UPDATE 
+    ON public.prop_picks
+    FOR EACH ROW
+    EXECUTE FUNCTION public.update_updated_at_column();
+
+-- scheduled rounds are needed to validate round props, the parsed result to grade them
+
+ALTER TABLE IF EXISTS public.matchup_schedule
+    ADD COLUMN IF NOT EXISTS scheduled_rounds integer;
+
+ALTER TABLE IF EXISTS public.matchup_results
+    ADD COLUMN IF NOT EXISTS result_text text,
+    ADD COLUMN IF NOT EXISTS method character varying(20),
+    ADD COLUMN IF NOT EXISTS round integer;
@@ -0,0 +1,15 @@
+-- Index: picks_user_id_result_idx
+
+-- DROP INDEX IF EXISTS public.picks_user_id_result_idx;
+
+CREATE INDEX IF NOT EXISTS picks_user_id_result_idx
+    ON public.picks USING btree
+    (user_id, pick_result);
+
+-- Index: matchup_results_graded_at_idx
+
+-- DROP INDEX IF EXISTS public.matchup_results_graded_at_idx;
+
+CREATE INDEX IF NOT EXISTS matchup_results_graded_at_idx
+    ON public.matchup_results USING btree
+    (graded_at);
@@ -0,0 +1,77 @@
+-- Table: public.leagues
+
+-- DROP TABLE IF EXISTS public.leagues;
+
+CREATE TABLE IF NOT EXISTS public.leagues
+(
+    league_id serial NOT NULL,
+    name character varying(100) COLLATE pg_catalog."default" NOT NULL,
+    created_by integer NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT leagues_pkey PRIMARY KEY (league_id),
+    CONSTRAINT fk_created_by FOREIGN KEY (created_by)
+        REFERENCES public.users (user_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.leagues
+    OWNER to introducing_first_users_user;
+
+-- Table: public.league_members
+
+-- DROP TABLE IF EXISTS public.league_members;
+
+CREATE TABLE IF NOT EXISTS public.league_members
+(
+    league_id integer NOT NULL,
+    user_id integer NOT NULL,
+    role character varying(20) COLLATE pg_catalog."default" NOT NULL DEFAULT 'member'::character varying,
+    joined_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT league_members_pkey PRIMARY KEY (league_id, user_id),
+    CONSTRAINT fk_league FOREIGN KEY (league_id)
+        REFERENCES public.leagues (league_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY (user_id)
+        REFERENCES public.users (user_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.league_members
+    OWNER to introducing_first_users_user;
+
+CREATE INDEX IF NOT EXISTS league_members_user_id_idx
+    ON public.league_members USING btree
+    (user_id);
+
+-- Table: public.league_invites
+
+-- DROP TABLE IF EXISTS public.league_invites;
+
+CREATE TABLE IF NOT EXISTS public.league_invites
+(
+    invite_code character varying(16) COLLATE pg_catalog."default" NOT NULL,
+    league_id integer NOT NULL,
+    created_by integer NOT NULL,
+    expires_at timestamp with time zone,
+    max_uses integer,
+    uses integer NOT NULL DEFAULT 0,
+    revoked boolean NOT NULL DEFAULT false,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT league_invites_pkey PRIMARY KEY (invite_code),
+    CONSTRAINT fk_league FOREIGN KEY (league_id)
+        REFERENCES public.leagues (league_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.league_invites
+    OWNER to introducing_first_users_user;
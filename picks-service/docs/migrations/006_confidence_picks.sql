@@ -0,0 +1,13 @@
+-- confidence points for the confidence pick mode. NULL for regular moneyline picks
+
+ALTER TABLE IF EXISTS public.picks
+    ADD COLUMN IF NOT EXISTS confidence integer;
+
+-- Index: picks_user_event_confidence_key
+
+-- DROP INDEX IF EXISTS public.picks_user_event_confidence_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS picks_user_event_confidence_key
+    ON public.picks USING btree
+    (user_id, event_id, confidence)
+    WHERE confidence IS NOT NULL;
@@ -0,0 +1,5 @@
+-- American odds of the selected fighter when the pick was made and when the fight started
+
+ALTER TABLE IF EXISTS public.picks
+    ADD COLUMN IF NOT EXISTS odds_at_pick integer,
+    ADD COLUMN IF NOT EXISTS closing_odds integer;
@@ -0,0 +1,21 @@
+-- Table: public.rate_limit_buckets
+
+-- DROP TABLE IF EXISTS public.rate_limit_buckets;
+
+CREATE TABLE IF NOT EXISTS public.rate_limit_buckets
+(
+    bucket_key character varying(255) COLLATE pg_catalog."default" NOT NULL,
+    tokens double precision NOT NULL,
+    allowed boolean NOT NULL DEFAULT true,
+    updated_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT rate_limit_buckets_pkey PRIMARY KEY (bucket_key)
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.rate_limit_buckets
+    OWNER to introducing_first_users_user;
+
+CREATE INDEX IF NOT EXISTS rate_limit_buckets_updated_at_idx
+    ON public.rate_limit_buckets USING btree
+    (updated_at);
@@ -0,0 +1,38 @@
+-- Table: public.pick_history
+-- append-only log of every pick insert and selection change, used for disputes and flip-flop stats
+
+-- DROP TABLE IF EXISTS public.pick_history;
+
+CREATE TABLE IF NOT EXISTS public.pick_history
+(
+    history_id serial NOT NULL,
+    pick_id integer NOT NULL,
+    user_id integer NOT NULL,
+    matchup_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    event_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    previous_selection_fighter_id character varying(32) COLLATE pg_catalog."default",
+    new_selection_fighter_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    change_type character varying(10) COLLATE pg_catalog."default" NOT NULL,
+    source character varying(20) COLLATE pg_catalog."default" NOT NULL,
+    source_ip character varying(64) COLLATE pg_catalog."default",
+    user_agent text COLLATE pg_catalog."default",
+    changed_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pick_history_pkey PRIMARY KEY (history_id),
+    CONSTRAINT fk_user FOREIGN KEY (user_id)
+        REFERENCES public.users (user_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.pick_history
+    OWNER to introducing_first_users_user;
+
+CREATE INDEX IF NOT EXISTS pick_history_user_event_idx
+    ON public.pick_history USING btree
+    (user_id, event_id);
+
+CREATE INDEX IF NOT EXISTS pick_history_matchup_id_idx
+    ON public.pick_history USING btree
+    (matchup_id);
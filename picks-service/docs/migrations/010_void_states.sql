@@ -0,0 +1,19 @@
+-- picks can now end up void (no contest), push (draw) or cancelled (scrapped bout or withdrawn fighter)
+-- besides pending / correct / incorrect. none of the three count towards users' totals or leaderboards
+
+ALTER TABLE IF EXISTS public.picks
+    DROP CONSTRAINT IF EXISTS picks_pick_result_check;
+
+ALTER TABLE IF EXISTS public.picks
+    ADD CONSTRAINT picks_pick_result_check
+    CHECK (pick_result IN ('pending', 'correct', 'incorrect', 'void', 'push', 'cancelled'));
+
+ALTER TABLE IF EXISTS public.prop_picks
+    DROP CONSTRAINT IF EXISTS prop_picks_result_check;
+
+ALTER TABLE IF EXISTS public.prop_picks
+    ADD CONSTRAINT prop_picks_result_check
+    CHECK (method_result IN ('pending', 'correct', 'incorrect', 'void', 'cancelled')
+        AND (round_result IS NULL OR round_result IN ('pending', 'correct', 'incorrect', 'void', 'cancelled')));
+
+-- matchup_results.outcome is one of win, draw, no_contest, draw_no_contest (scraper didn't say) or cancelled
@@ -0,0 +1,4 @@
+-- weight class of each matchup, synced with the schedule, for per-division pick analytics
+
+ALTER TABLE IF EXISTS public.matchup_schedule
+    ADD COLUMN IF NOT EXISTS weight_class character varying(50);
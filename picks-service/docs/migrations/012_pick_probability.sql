@@ -0,0 +1,11 @@
+-- optional win probability of the selected fighter, for Brier / log-loss scoring and calibration
+
+ALTER TABLE IF EXISTS public.picks
+    ADD COLUMN IF NOT EXISTS probability double precision;
+
+ALTER TABLE IF EXISTS public.picks
+    DROP CONSTRAINT IF EXISTS picks_probability_check;
+
+ALTER TABLE IF EXISTS public.picks
+    ADD CONSTRAINT picks_probability_check
+    CHECK (probability IS NULL OR (probability >= 0.5 AND probability <= 0.99));
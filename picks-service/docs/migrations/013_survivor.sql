@@ -0,0 +1,85 @@
+-- Table: public.survivor_contests
+
+-- DROP TABLE IF EXISTS public.survivor_contests;
+
+CREATE TABLE IF NOT EXISTS public.survivor_contests
+(
+    contest_id serial NOT NULL,
+    name character varying(100) COLLATE pg_catalog."default" NOT NULL,
+    created_by integer NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT survivor_contests_pkey PRIMARY KEY (contest_id),
+    CONSTRAINT fk_created_by FOREIGN KEY (created_by)
+        REFERENCES public.users (user_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.survivor_contests
+    OWNER to introducing_first_users_user;
+
+-- Table: public.survivor_entries
+
+-- DROP TABLE IF EXISTS public.survivor_entries;
+
+CREATE TABLE IF NOT EXISTS public.survivor_entries
+(
+    contest_id integer NOT NULL,
+    user_id integer NOT NULL,
+    status character varying(20) COLLATE pg_catalog."default" NOT NULL DEFAULT 'alive'::character varying,
+    eliminated_event_id character varying(32) COLLATE pg_catalog."default",
+    joined_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT survivor_entries_pkey PRIMARY KEY (contest_id, user_id),
+    CONSTRAINT fk_contest FOREIGN KEY (contest_id)
+        REFERENCES public.survivor_contests (contest_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY (user_id)
+        REFERENCES public.users (user_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.survivor_entries
+    OWNER to introducing_first_users_user;
+
+-- Table: public.survivor_picks
+-- one pick per entry per event. result follows the same values as picks.pick_result
+
+-- DROP TABLE IF EXISTS public.survivor_picks;
+
+CREATE TABLE IF NOT EXISTS public.survivor_picks
+(
+    contest_id integer NOT NULL,
+    user_id integer NOT NULL,
+    event_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    matchup_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    fighter_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    result character varying(50) COLLATE pg_catalog."default" NOT NULL DEFAULT 'pending'::character varying,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT survivor_picks_pkey PRIMARY KEY (contest_id, user_id, event_id),
+    CONSTRAINT fk_entry FOREIGN KEY (contest_id, user_id)
+        REFERENCES public.survivor_entries (contest_id, user_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.survivor_picks
+    OWNER to introducing_first_users_user;
+
+-- a fighter can only be used once per entry, unless the bout was cancelled
+CREATE UNIQUE INDEX IF NOT EXISTS survivor_picks_fighter_used_idx
+    ON public.survivor_picks USING btree
+    (contest_id, user_id, fighter_id)
+    WHERE result <> 'cancelled';
+
+CREATE INDEX IF NOT EXISTS survivor_picks_matchup_idx
+    ON public.survivor_picks USING btree
+    (matchup_id, event_id);
@@ -0,0 +1,75 @@
+-- Table: public.event_dates
+-- date of each event, synced with the schedule. picks count towards the season their event falls in
+
+-- DROP TABLE IF EXISTS public.event_dates;
+
+CREATE TABLE IF NOT EXISTS public.event_dates
+(
+    event_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    event_date date NOT NULL,
+    CONSTRAINT event_dates_pkey PRIMARY KEY (event_id)
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.event_dates
+    OWNER to introducing_first_users_user;
+
+CREATE INDEX IF NOT EXISTS event_dates_event_date_idx
+    ON public.event_dates USING btree
+    (event_date);
+
+-- Table: public.seasons
+
+-- DROP TABLE IF EXISTS public.seasons;
+
+CREATE TABLE IF NOT EXISTS public.seasons
+(
+    season_id serial NOT NULL,
+    name character varying(100) COLLATE pg_catalog."default" NOT NULL,
+    starts_on date NOT NULL,
+    ends_on date NOT NULL,
+    status character varying(20) COLLATE pg_catalog."default" NOT NULL DEFAULT 'active'::character varying,
+    closed_at timestamp with time zone,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT seasons_pkey PRIMARY KEY (season_id),
+    CONSTRAINT seasons_dates_check CHECK (ends_on >= starts_on)
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.seasons
+    OWNER to introducing_first_users_user;
+
+-- Table: public.season_standings
+-- final standings frozen when a season closes. username is kept as it was at the time
+
+-- DROP TABLE IF EXISTS public.season_standings;
+
+CREATE TABLE IF NOT EXISTS public.season_standings
+(
+    season_id integer NOT NULL,
+    user_id integer NOT NULL,
+    rank integer NOT NULL,
+    username character varying(255) COLLATE pg_catalog."default" NOT NULL,
+    correct integer NOT NULL,
+    total integer NOT NULL,
+    CONSTRAINT season_standings_pkey PRIMARY KEY (season_id, user_id),
+    CONSTRAINT fk_season FOREIGN KEY (season_id)
+        REFERENCES public.seasons (season_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY (user_id)
+        REFERENCES public.users (user_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.season_standings
+    OWNER to introducing_first_users_user;
+
+CREATE INDEX IF NOT EXISTS season_standings_rank_idx
+    ON public.season_standings USING btree
+    (season_id, rank);
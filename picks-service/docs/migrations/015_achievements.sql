@@ -0,0 +1,22 @@
+-- Table: public.user_achievements
+-- badges awarded to users. the rules themselves are defined in code, keyed by achievement_code
+
+-- DROP TABLE IF EXISTS public.user_achievements;
+
+CREATE TABLE IF NOT EXISTS public.user_achievements
+(
+    user_id integer NOT NULL,
+    achievement_code character varying(50) COLLATE pg_catalog."default" NOT NULL,
+    event_id character varying(32) COLLATE pg_catalog."default",
+    awarded_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT user_achievements_pkey PRIMARY KEY (user_id, achievement_code),
+    CONSTRAINT fk_user FOREIGN KEY (user_id)
+        REFERENCES public.users (user_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.user_achievements
+    OWNER to introducing_first_users_user;
@@ -0,0 +1,8 @@
+-- event and fighter names of each matchup, synced with the schedule, so pick exports are readable without the scraper
+
+ALTER TABLE IF EXISTS public.matchup_schedule
+    ADD COLUMN IF NOT EXISTS event_name character varying(255),
+    ADD COLUMN IF NOT EXISTS fighter1_id character varying(32),
+    ADD COLUMN IF NOT EXISTS fighter1_name character varying(255),
+    ADD COLUMN IF NOT EXISTS fighter2_id character varying(32),
+    ADD COLUMN IF NOT EXISTS fighter2_name character varying(255);
@@ -0,0 +1,35 @@
+-- one moneyline pick per user per matchup. concurrent upserts used to be able to insert the same pick twice,
+-- so duplicates are removed first: the most recently updated row is kept, and whatever the removed rows
+-- added to the users' counters is taken back off
+
+BEGIN;
+
+CREATE TEMPORARY TABLE duplicate_picks ON COMMIT DROP AS
+SELECT pick_id, user_id, pick_result
+FROM (
+    SELECT pick_id, user_id, pick_result,
+        ROW_NUMBER() OVER (PARTITION BY user_id, matchup_id, event_id ORDER BY updated_at DESC NULLS LAST, pick_id DESC) AS position
+    FROM public.picks
+) ranked
+WHERE position > 1;
+
+UPDATE public.users u
+SET total_picks = GREATEST(COALESCE(u.total_picks, 0) - d.counted, 0),
+    total_correct_picks = GREATEST(COALESCE(u.total_correct_picks, 0) - d.correct, 0)
+FROM (
+    SELECT user_id,
+        COUNT(*) FILTER (WHERE pick_result IN ('correct', 'incorrect')) AS counted,
+        COUNT(*) FILTER (WHERE pick_result = 'correct') AS correct
+    FROM duplicate_picks
+    GROUP BY user_id
+) d
+WHERE u.user_id = d.user_id;
+
+DELETE FROM public.picks WHERE pick_id IN (SELECT pick_id FROM duplicate_picks);
+
+-- ALTER TABLE IF EXISTS public.picks DROP CONSTRAINT IF EXISTS picks_user_matchup_event_key;
+
+ALTER TABLE IF EXISTS public.picks
+    ADD CONSTRAINT picks_user_matchup_event_key UNIQUE (user_id, matchup_id, event_id);
+
+COMMIT;
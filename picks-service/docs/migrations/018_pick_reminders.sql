@@ -0,0 +1,23 @@
+-- Table: public.pick_reminders
+-- one row per reminder sent to a user for an event at a given offset before lock. the row is claimed before
+-- sending, so every instance of the service can run the scheduler without users getting the reminder twice
+
+-- DROP TABLE IF EXISTS public.pick_reminders;
+
+CREATE TABLE IF NOT EXISTS public.pick_reminders
+(
+    user_id integer NOT NULL,
+    event_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    offset_minutes integer NOT NULL,
+    sent_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pick_reminders_pkey PRIMARY KEY (user_id, event_id, offset_minutes),
+    CONSTRAINT fk_user FOREIGN KEY (user_id)
+        REFERENCES public.users (user_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.pick_reminders
+    OWNER to introducing_first_users_user;
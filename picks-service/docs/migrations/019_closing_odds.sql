@@ -0,0 +1,20 @@
+-- Table: public.matchup_closing_odds
+-- the odds of each fighter captured when the matchup locked. grading copies them onto the picks as closing_odds,
+-- so CLV is measured against the line when the fight started rather than a quote taken after it ended
+
+-- DROP TABLE IF EXISTS public.matchup_closing_odds;
+
+CREATE TABLE IF NOT EXISTS public.matchup_closing_odds
+(
+    matchup_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    event_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    fighter_id character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    american integer NOT NULL,
+    captured_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT matchup_closing_odds_pkey PRIMARY KEY (matchup_id, fighter_id)
+)
+
+TABLESPACE pg_default;
+
+ALTER TABLE IF EXISTS public.matchup_closing_odds
+    OWNER to introducing_first_users_user;
@@ -0,0 +1,180 @@
+// events provides the event cards picks are validated against: which matchups an event has and who fights in them
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrEventNotFound = errors.New("event not found")
+
+// Matchup is one bout of an event card as the scraper service reports it
+type Matchup struct {
+	MatchupID    string `json:"matchup_id"`
+	Fighter1ID   string `json:"fighter1_id"`
+	Fighter2ID   string `json:"fighter2_id"`
+	Fighter1Name string `json:"fighter1_name"`
+	Fighter2Name string `json:"fighter2_name"`
+}
+
+// HasFighter reports whether fighterID is one of the two fighters of the matchup
+func (m Matchup) HasFighter(fighterID string) bool {
+	return fighterID != "" && (fighterID == m.Fighter1ID || fighterID == m.Fighter2ID)
+}
+
+type Event struct {
+	EventID  string    `json:"event_id"`
+	Name     string    `json:"name"`
+	Date     string    `json:"date"`
+	Matchups []Matchup `json:"matchups"`
+}
+
+// Matchup finds a matchup of the event's card by id
+func (e Event) Matchup(matchupID string) (Matchup, bool) {
+	for _, m := range e.Matchups {
+		if m.MatchupID == matchupID {
+			return m, true
+		}
+	}
+	return Matchup{}, false
+}
+
+// Source is anything that can look up an event card
+type Source interface {
+	GetEvent(ctx context.Context, eventID string) (Event, error)
+}
+
+// DefaultCacheTTL is how long HTTPSource reuses a fetched card. Cards rarely change close to lock
+// and every pick is validated, so this keeps the scraper service out of the hot path
+const DefaultCacheTTL = time.Minute
+
+// HTTPSource reads event cards from the scraper service's GET /api/events/{id}
+type HTTPSource struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+	ttl     time.Duration
+
+	mu     sync.Mutex
+	cached map[string]cachedEvent
+}
+
+type cachedEvent struct {
+	event     Event
+	fetchedAt time.Time
+}
+
+// NewHTTPSource reads cards from the scraper service at baseURL (SCRAPER_SERVICE_URL).
+// apiKey is sent as X-API-Key when set
+func NewHTTPSource(baseURL string, apiKey string) *HTTPSource {
+	return &HTTPSource{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		ttl:     DefaultCacheTTL,
+		cached:  make(map[string]cachedEvent),
+	}
+}
+
+func (s *HTTPSource) GetEvent(ctx context.Context, eventID string) (Event, error) {
+	s.mu.Lock()
+	entry, ok := s.cached[eventID]
+	s.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < s.ttl {
+		return entry.event, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/api/events/"+url.PathEscape(eventID), nil)
+	if err != nil {
+		return Event{}, fmt.Errorf("unable to build event request: %w", err)
+	}
+	if s.apiKey != "" {
+		req.Header.Set("X-API-Key", s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Event{}, fmt.Errorf("unable to reach scraper service for event %s: %w", eventID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Event{}, ErrEventNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Event{}, fmt.Errorf("scraper service returned %d for event %s", resp.StatusCode, eventID)
+	}
+
+	//the scraper service answers 200 with a null body for unknown events
+	var event *Event
+	if err := json.NewDecoder(resp.Body).Decode(&event); err != nil {
+		return Event{}, fmt.Errorf("unable to decode event %s: %w", eventID, err)
+	}
+	if event == nil || event.EventID == "" {
+		return Event{}, ErrEventNotFound
+	}
+
+	s.mu.Lock()
+	s.cached[eventID] = cachedEvent{event: *event, fetchedAt: time.Now()}
+	s.mu.Unlock()
+	return *event, nil
+}
+
+// JSONFileSource serves event cards from a local JSON file holding an array of events in the scraper
+// service's format, which makes it usable as a fixture in tests and for local runs
+type JSONFileSource struct {
+	path   string
+	mu     sync.RWMutex
+	events map[string]Event
+}
+
+func NewJSONFileSource(path string) (*JSONFileSource, error) {
+	s := &JSONFileSource{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload re-reads the file so cards can be updated without a restart
+func (s *JSONFileSource) Reload() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("unable to read events file %s: %w", s.path, err)
+	}
+
+	var parsed []Event
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return fmt.Errorf("unable to parse events file %s: %w", s.path, err)
+	}
+
+	byId := make(map[string]Event, len(parsed))
+	for _, e := range parsed {
+		byId[e.EventID] = e
+	}
+
+	s.mu.Lock()
+	s.events = byId
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *JSONFileSource) GetEvent(ctx context.Context, eventID string) (Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event, ok := s.events[eventID]
+	if !ok {
+		return Event{}, ErrEventNotFound
+	}
+	event.Matchups = append([]Matchup(nil), event.Matchups...)
+	return event, nil
+}
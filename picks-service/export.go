@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"picks-service/db"
+)
+
+var exportCSVHeader = []string{
+	"pick_id", "event_id", "event_name", "event_date", "matchup_id", "card_type", "weight_class",
+	"selection_fighter_id", "selection_name", "opponent_fighter_id", "opponent_name",
+	"odds_at_pick", "closing_odds", "confidence", "probability", "pick_result",
+	"outcome", "winner_fighter_id", "method", "round", "created_at", "graded_at",
+}
+
+// streams every pick of a user as CSV (default) or NDJSON. users export their own picks, admins anyone's
+func exportPicksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authUserId, ok := authenticatedUserId(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userId := authUserId
+	if param := r.URL.Query().Get("userId"); param != "" {
+		var err error
+		userId, err = strconv.Atoi(param)
+		if err != nil {
+			http.Error(w, "Invalid userId: must be an integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "ndjson" {
+		http.Error(w, "Invalid format: must be csv or ndjson", http.StatusBadRequest)
+		return
+	}
+
+	if userId != authUserId && !requireAdmin(w, authUserId) {
+		return
+	}
+
+	filename := fmt.Sprintf("picks-%d-%s.%s", userId, time.Now().UTC().Format("20060102"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	flusher, _ := w.(http.Flusher)
+
+	//once rows are written the status is sent, so errors past that point can only cut the stream short
+	var err error
+	written := 0
+	if format == "ndjson" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		err = db.StreamPicksForUser(r.Context(), userId, func(p db.ExportedPick) error {
+			written++
+			if flusher != nil && written%db.ExportFetchSize == 0 {
+				flusher.Flush()
+			}
+			return encoder.Encode(p)
+		})
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		writer := csv.NewWriter(w)
+		//the header waits for the first row so a query that fails up front can still answer with a 500
+		err = db.StreamPicksForUser(r.Context(), userId, func(p db.ExportedPick) error {
+			if written == 0 {
+				if err := writer.Write(exportCSVHeader); err != nil {
+					return err
+				}
+			}
+			written++
+			if written%db.ExportFetchSize == 0 {
+				writer.Flush()
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			return writer.Write(exportCSVRecord(p))
+		})
+		if err == nil && written == 0 {
+			err = writer.Write(exportCSVHeader)
+		}
+		if err == nil || written > 0 {
+			writer.Flush()
+			if err == nil {
+				err = writer.Error()
+			}
+		}
+	}
+
+	if err != nil {
+		log.Printf("Error exporting picks for user %d after %d rows: %v", userId, written, err)
+		if written == 0 {
+			w.Header().Del("Content-Disposition")
+			http.Error(w, "Error exporting picks", http.StatusInternalServerError)
+		}
+	}
+}
+
+func exportCSVRecord(p db.ExportedPick) []string {
+	var eventDate string
+	if p.EventDate != nil {
+		eventDate = p.EventDate.Format("2006-01-02")
+	}
+	var gradedAt string
+	if p.GradedAt != nil {
+		gradedAt = p.GradedAt.UTC().Format(time.RFC3339)
+	}
+	var probability string
+	if p.Probability != nil {
+		probability = strconv.FormatFloat(*p.Probability, 'f', -1, 64)
+	}
+
+	return []string{
+		strconv.Itoa(p.PickID), p.EventID, csvString(p.EventName), eventDate, p.MatchupID, csvString(p.CardType), csvString(p.WeightClass),
+		p.SelectionID, csvString(p.Selection), csvString(p.OpponentID), csvString(p.Opponent),
+		csvInt(p.OddsAtPick), csvInt(p.ClosingOdds), csvInt(p.Confidence), probability, p.PickResult,
+		csvString(p.Outcome), csvString(p.WinnerID), csvString(p.Method), csvInt(p.Round),
+		p.CreatedAt.UTC().Format(time.RFC3339), gradedAt,
+	}
+}
+
+func csvString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func csvInt(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return strconv.Itoa(*i)
+}
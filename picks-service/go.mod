@@ -3,8 +3,11 @@ module picks-service
 go 1.23.1
 
 require (
-	github.com/golang-jwt/jwt/v4 v4.5.1
+	github.com/golang-jwt/jwt/v4 v4.5.1 // indirect
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
-	golang.org/x/crypto v0.28.0
 )
+
+require shared v0.0.0
+
+replace shared => ../shared
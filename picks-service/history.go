@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"picks-service/db"
+)
+
+// pickMetaFromRequest captures where a pick write came from for the pick history
+func pickMetaFromRequest(r *http.Request, source string) db.PickMeta {
+	return db.PickMeta{
+		Source:    source,
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// trustedProxyHops is how many proxies in front of the service append to X-Forwarded-For (TRUSTED_PROXY_HOPS).
+// Entries left of those were sent by the client and can't be trusted
+var trustedProxyHops = 1
+
+// clientIP takes the X-Forwarded-For entry added by the outermost trusted proxy, falling back to the
+// connection's address when there are no trusted proxies or the header is shorter than expected
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Values("X-Forwarded-For"); trustedProxyHops > 0 && len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		if len(hops) >= trustedProxyHops {
+			if ip := strings.TrimSpace(hops[len(hops)-trustedProxyHops]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// a user's pick changes and flip-flop stats. users can see their own history, admins anyone's
+func getPickHistoryForUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authUserId, ok := authenticatedUserId(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userId := authUserId
+	if param := r.URL.Query().Get("userId"); param != "" {
+		var err error
+		userId, err = strconv.Atoi(param)
+		if err != nil {
+			http.Error(w, "Invalid userId: must be an integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if userId != authUserId && !requireAdmin(w, authUserId) {
+		return
+	}
+
+	query, err := parseListQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	history, err := db.GetPickHistoryForUser(userId, r.URL.Query().Get("eventId"), query)
+	if errors.Is(err, db.ErrInvalidListQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving pick history for user %d: %v", userId, err)
+		http.Error(w, "Error retrieving pick history", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, history)
+}
+
+// every change made on a matchup's picks, for resolving disputes. admin only
+func getPickHistoryForMatchupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authUserId, ok := authenticatedUserId(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !requireAdmin(w, authUserId) {
+		return
+	}
+
+	matchupId := r.URL.Query().Get("matchupId")
+	if matchupId == "" {
+		http.Error(w, "matchupId is required", http.StatusBadRequest)
+		return
+	}
+
+	query, err := parseListQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changes, err := db.GetPickHistoryForMatchup(matchupId, query)
+	if errors.Is(err, db.ErrInvalidListQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving pick history for matchup %s: %v", matchupId, err)
+		http.Error(w, "Error retrieving pick history", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, changes)
+}
+
+// requireAdmin writes a 403 (or 500) and returns false unless the user is an admin
+func requireAdmin(w http.ResponseWriter, userId int) bool {
+	isAdmin, err := db.IsAdmin(userId)
+	if err != nil {
+		log.Printf("Error checking admin role: %v", err)
+		http.Error(w, "Error checking permissions", http.StatusInternalServerError)
+		return false
+	}
+	if !isAdmin {
+		http.Error(w, "Admin role required", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"picks-service/db"
+)
+
+// parsePaging reads limit and offset query parameters. missing values are left at 0 for the db defaults
+func parsePaging(r *http.Request) (int, int, error) {
+	var limit, offset int
+	var err error
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit < 0 {
+			return 0, 0, fmt.Errorf("invalid limit: must be a positive integer")
+		}
+	}
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if offset, err = strconv.Atoi(offsetStr); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset: must be a positive integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+// parseLeaderboardQuery reads scope, eventId, season, limit, offset and cursor query parameters
+func parseLeaderboardQuery(r *http.Request) (db.LeaderboardQuery, error) {
+	scope, err := db.ParseLeaderboardScope(r.URL.Query().Get("scope"))
+	if err != nil {
+		return db.LeaderboardQuery{}, err
+	}
+
+	limit, offset, err := parsePaging(r)
+	if err != nil {
+		return db.LeaderboardQuery{}, err
+	}
+
+	query := db.LeaderboardQuery{Scope: scope, EventID: r.URL.Query().Get("eventId"), Limit: limit, Offset: offset, Cursor: r.URL.Query().Get("cursor")}
+	switch scope {
+	case db.LeaderboardEvent:
+		if query.EventID == "" {
+			return query, fmt.Errorf("missing query parameter: eventId")
+		}
+	case db.LeaderboardSeason:
+		//no season means the current one
+		if seasonStr := r.URL.Query().Get("season"); seasonStr != "" {
+			seasonId, err := strconv.Atoi(seasonStr)
+			if err != nil || seasonId <= 0 {
+				return query, fmt.Errorf("invalid season: must be a season id")
+			}
+			query.SeasonID = seasonId
+		}
+	}
+	return query, nil
+}
+
+// leaderboard for scope=all|event|season|90d. signed in users also get their own rank in "me"
+func getLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query, err := parseLeaderboardQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	board, err := db.GetLeaderboard(query, optionalUserId(r))
+	if errors.Is(err, db.ErrSeasonNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, db.ErrInvalidListQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving %s leaderboard: %v", query.Scope, err)
+		http.Error(w, "Error retrieving leaderboard", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(board); err != nil {
+		http.Error(w, "Error encoding leaderboard to JSON", http.StatusInternalServerError)
+	}
+}
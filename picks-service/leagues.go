@@ -0,0 +1,304 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"picks-service/db"
+)
+
+// leagueErrorStatus maps league errors to HTTP status codes
+func leagueErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrLeagueNotFound), errors.Is(err, db.ErrSeasonNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrNotLeagueMember), errors.Is(err, db.ErrNotLeagueAdmin):
+		return http.StatusForbidden
+	case errors.Is(err, db.ErrInvalidInvite), errors.Is(err, db.ErrInvalidLeagueArg), errors.Is(err, db.ErrInvalidListQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrLastLeagueAdmin):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// writeLeagueError responds with the mapped status, hiding unexpected errors behind message
+func writeLeagueError(w http.ResponseWriter, err error, message string) {
+	status := leagueErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, status)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func createLeagueHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	league, err := db.CreateLeague(req.Name, userId)
+	if err != nil {
+		writeLeagueError(w, err, "Error creating league")
+		return
+	}
+
+	writeJSON(w, league)
+}
+
+func getMyLeaguesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+
+	query, err := parseListQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	leagues, err := db.GetLeaguesForUser(userId, query)
+	if err != nil {
+		writeLeagueError(w, err, "Error retrieving leagues")
+		return
+	}
+
+	writeJSON(w, leagues)
+}
+
+// creates an invite code for a league. admins only; expiresInHours and maxUses are optional
+func createLeagueInviteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+
+	var req struct {
+		LeagueID       int `json:"leagueId"`
+		ExpiresInHours int `json:"expiresInHours"`
+		MaxUses        int `json:"maxUses"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInHours > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		expiresAt = &t
+	}
+
+	invite, err := db.CreateLeagueInvite(req.LeagueID, userId, expiresAt, req.MaxUses)
+	if err != nil {
+		writeLeagueError(w, err, "Error creating league invite")
+		return
+	}
+
+	writeJSON(w, invite)
+}
+
+func revokeLeagueInviteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+
+	var req struct {
+		InviteCode string `json:"inviteCode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.RevokeLeagueInvite(req.InviteCode, userId); err != nil {
+		writeLeagueError(w, err, "Error revoking league invite")
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Invite revoked"})
+}
+
+func joinLeagueHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+
+	var req struct {
+		InviteCode string `json:"inviteCode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	league, err := db.JoinLeague(req.InviteCode, userId)
+	if err != nil {
+		writeLeagueError(w, err, "Error joining league")
+		return
+	}
+
+	writeJSON(w, league)
+}
+
+func leaveLeagueHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+
+	var req struct {
+		LeagueID int `json:"leagueId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.LeaveLeague(req.LeagueID, userId); err != nil {
+		writeLeagueError(w, err, "Error leaving league")
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Left league"})
+}
+
+// promotes (role=admin) or demotes (role=member) a league member. admins only
+func setLeagueMemberRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+
+	var req struct {
+		LeagueID int    `json:"leagueId"`
+		UserID   int    `json:"userId"`
+		Role     string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.SetLeagueMemberRole(req.LeagueID, userId, req.UserID, req.Role); err != nil {
+		writeLeagueError(w, err, "Error updating league role")
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Role updated"})
+}
+
+func removeLeagueMemberHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+
+	var req struct {
+		LeagueID int `json:"leagueId"`
+		UserID   int `json:"userId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.RemoveLeagueMember(req.LeagueID, userId, req.UserID); err != nil {
+		writeLeagueError(w, err, "Error removing league member")
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Member removed"})
+}
+
+func getLeagueMembersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+
+	leagueId, err := strconv.Atoi(r.URL.Query().Get("leagueId"))
+	if err != nil {
+		http.Error(w, "Invalid leagueId: must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	query, err := parseListQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	members, err := db.GetLeagueMembers(leagueId, userId, query)
+	if err != nil {
+		writeLeagueError(w, err, "Error retrieving league members")
+		return
+	}
+
+	writeJSON(w, members)
+}
+
+// same parameters as /api/v1/getLeaderboard plus leagueId
+func getLeagueLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+
+	leagueId, err := strconv.Atoi(r.URL.Query().Get("leagueId"))
+	if err != nil {
+		http.Error(w, "Invalid leagueId: must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	query, err := parseLeaderboardQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	board, err := db.GetLeagueLeaderboard(leagueId, query, userId)
+	if err != nil {
+		writeLeagueError(w, err, "Error retrieving league leaderboard")
+		return
+	}
+
+	writeJSON(w, board)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"picks-service/ratelimit"
+)
+
+// pick writes are limited to one every 2 seconds per key
+var pickRate = ratelimit.Rate{Burst: 1, Every: 2 * time.Second}
+
+var pickLimiter ratelimit.Limiter = ratelimit.NewMemoryLimiter(pickRate)
+
+// newPickLimiter picks the limiter from RATE_LIMITER: "postgres" shares limits across replicas,
+// anything else keeps them in memory
+func newPickLimiter(usersDb *sql.DB) ratelimit.Limiter {
+	if getEnvWithFallback("RATE_LIMITER", "memory") == "postgres" {
+		log.Printf("Using Postgres rate limiter")
+		return ratelimit.NewPostgresLimiter(usersDb, pickRate)
+	}
+	return ratelimit.NewMemoryLimiter(pickRate)
+}
+
+// rateLimit rejects requests over pickLimiter's rate for the key returned by keyFunc with a 429 and
+// Retry-After header. it must run after authenticate since keys are per user
+func rateLimit(keyFunc func(r *http.Request) string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := keyFunc(r)
+
+		decision, err := pickLimiter.Allow(r.Context(), key)
+		if err != nil {
+			//a broken limiter shouldn't take picks down with it
+			log.Printf("Rate limiter error, allowing request: %v", err)
+			handler(w, r)
+			return
+		}
+
+		if !decision.Allowed {
+			seconds := math.Ceil(decision.RetryAfter.Seconds())
+			log.Printf("Rate limit hit for %s - Time remaining: %.1f seconds", key, decision.RetryAfter.Seconds())
+			w.Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+			http.Error(w, fmt.Sprintf("rate limit exceeded: please wait %.1f seconds before updating your pick again", decision.RetryAfter.Seconds()), http.StatusTooManyRequests)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// pickRateKey limits each user per matchup, so picking several fights in a row isn't throttled
+func pickRateKey(r *http.Request) string {
+	userId, _ := authenticatedUserId(r)
+	return fmt.Sprintf("pick:%d:%s:%s", userId, r.FormValue("matchupId"), r.FormValue("eventId"))
+}
+
+func propPickRateKey(r *http.Request) string {
+	userId, _ := authenticatedUserId(r)
+	return fmt.Sprintf("prop:%d:%s:%s", userId, r.FormValue("matchupId"), r.FormValue("eventId"))
+}
+
+// userRouteRateKey limits each user per endpoint, for JSON endpoints that cover a whole card
+func userRouteRateKey(r *http.Request) string {
+	userId, _ := authenticatedUserId(r)
+	return fmt.Sprintf("%s:%d", r.URL.Path, userId)
+}
@@ -0,0 +1,68 @@
+// Package live fans event updates out to the clients streaming that event
+package live
+
+import "sync"
+
+// Message is one server-sent event: Event names it, Data is its JSON body
+type Message struct {
+	Event string
+	Data  []byte
+}
+
+// how many messages a slow client can fall behind before new ones are dropped for it
+const subscriberBuffer = 16
+
+// Broker keeps the subscribers of every event in this process. Updates from other instances reach it
+// through the caller (Postgres LISTEN), so the broker itself never needs to be shared
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan Message]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[string]map[chan Message]struct{})}
+}
+
+// Subscribe returns a channel of the event's messages and a function that must be called to unsubscribe
+func (b *Broker) Subscribe(eventID string) (<-chan Message, func()) {
+	ch := make(chan Message, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[eventID] == nil {
+		b.subscribers[eventID] = make(map[chan Message]struct{})
+	}
+	b.subscribers[eventID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers[eventID], ch)
+			if len(b.subscribers[eventID]) == 0 {
+				delete(b.subscribers, eventID)
+			}
+		})
+	}
+}
+
+// Publish sends msg to every subscriber of the event without blocking on slow ones
+func (b *Broker) Publish(eventID string, msg Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[eventID] {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// Subscribers is how many clients are streaming the event, so callers can skip building unwanted messages
+func (b *Broker) Subscribers(eventID string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers[eventID])
+}
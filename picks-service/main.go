@@ -2,16 +2,23 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/joho/godotenv"
 
+	"shared/auth"
+
 	"picks-service/db"
+	"picks-service/events"
+	"picks-service/odds"
 
 	_ "github.com/lib/pq"
 )
@@ -38,6 +45,7 @@ func enableCORS(handler http.HandlerFunc) http.HandlerFunc {
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept, Authorization, Cookie, X-Requested-With")
 			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+			w.Header().Set("Access-Control-Expose-Headers", "Retry-After")
 			w.Header().Set("Vary", "Origin")
 		}
 
@@ -50,16 +58,129 @@ func enableCORS(handler http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// requireAPIKey guards service-to-service endpoints (scrapers, admin scripts) with the shared X-API-Key header
+func requireAPIKey(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apiKey := os.Getenv("VALID_API_KEY")
+		if apiKey == "" || r.Header.Get("X-API-Key") != apiKey {
+			http.Error(w, "Forbidden: Invalid API Key", http.StatusForbidden)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
 func main() {
 	_ = godotenv.Load()
+	key, err := auth.SigningKeyFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	jwtKey = key
+	lockMode, err := db.ParseLockMode(getEnvWithFallback("PICK_LOCK_MODE", string(db.LockAtCardStart)))
+	if err != nil {
+		log.Fatalf("Invalid PICK_LOCK_MODE: %v", err)
+	}
+
+	//0 when the service is reached directly, so X-Forwarded-For is ignored
+	trustedProxyHops, err = strconv.Atoi(getEnvWithFallback("TRUSTED_PROXY_HOPS", "1"))
+	if err != nil || trustedProxyHops < 0 {
+		log.Fatalf("Invalid TRUSTED_PROXY_HOPS: must be a non-negative number of proxies")
+	}
+
+	//odds are optional; without a source picks are stored without odds
+	var oddsSource odds.Source
+	if oddsPath := os.Getenv("ODDS_FIXTURE_PATH"); oddsPath != "" {
+		source, err := odds.NewJSONFileSource(oddsPath)
+		if err != nil {
+			log.Fatalf("Error loading odds fixture: %v", err)
+		}
+		oddsSource = source
+	}
+
+	//picks are checked against the real card when the scraper service (or a fixture) is configured
+	var eventSource events.Source
+	if scraperURL := os.Getenv("SCRAPER_SERVICE_URL"); scraperURL != "" {
+		eventSource = events.NewHTTPSource(scraperURL, os.Getenv("VALID_API_KEY"))
+	} else if eventsPath := os.Getenv("EVENTS_FIXTURE_PATH"); eventsPath != "" {
+		source, err := events.NewJSONFileSource(eventsPath)
+		if err != nil {
+			log.Fatalf("Error loading events fixture: %v", err)
+		}
+		eventSource = source
+	} else {
+		log.Printf("SCRAPER_SERVICE_URL is not set, picks will not be validated against event cards")
+	}
 
-	db.StartUsersDbConnection()
+	usersDb := db.StartUsersDbConnection()
+	pickStore := db.NewPostgresPickStore(usersDb, lockMode, eventSource, oddsSource)
+	pickLimiter = newPickLimiter(usersDb)
+	go db.ListenForEventUpdates(handleEventUpdate)
+
+	//closing odds are taken as each matchup locks, there's nothing to capture without a source
+	if oddsSource != nil {
+		go runClosingOddsCapture(pickStore)
+	}
+
+	//reminders before lock. REMINDER_OFFSETS=off turns them off
+	reminderOffsets, err := parseReminderOffsets(getEnvWithFallback("REMINDER_OFFSETS", "24h,1h"))
+	if err != nil {
+		log.Fatalf("Error reading reminder offsets: %v", err)
+	}
+	if len(reminderOffsets) > 0 {
+		notifier, err := newReminderNotifier()
+		if err != nil {
+			log.Fatalf("Error configuring reminders: %v", err)
+		}
+		go runReminderScheduler(notifier, reminderOffsets, lockMode)
+	}
 
 	http.HandleFunc("/", handleRoot)
-	http.HandleFunc("/insertPick", enableCORS(insertPickHandler))
-	http.HandleFunc("/api/v1/getPicksForEvent", enableCORS(getPicksForEventHandler))
-	http.HandleFunc("/api/v1/getPicksForUserAndEvent", enableCORS(getPicksForUserAndEventHandler))
-	http.HandleFunc("/api/v1/getPicksForMatchup", enableCORS(getPicksForMatchupHandler))
+	http.HandleFunc("/insertPick", enableCORS(authenticate(rateLimit(pickRateKey, insertPickHandler(pickStore)))))
+	http.HandleFunc("/api/v1/getPicksForEvent", enableCORS(authenticate(getPicksForEventHandler(pickStore))))
+	http.HandleFunc("/api/v1/getPicksForUserAndEvent", enableCORS(authenticate(getPicksForUserAndEventHandler(pickStore))))
+	http.HandleFunc("/api/v1/getPicksForMatchup", enableCORS(authenticate(getPicksForMatchupHandler(pickStore))))
+	http.HandleFunc("/api/v1/getConsensusForMatchup", enableCORS(getConsensusForMatchupHandler))
+	http.HandleFunc("/api/v1/getConsensusForEvent", enableCORS(getConsensusForEventHandler))
+	http.HandleFunc("/api/v1/streamEvent", enableCORS(streamEventHandler))
+	http.HandleFunc("/api/v1/insertPicksBatch", enableCORS(authenticate(rateLimit(userRouteRateKey, insertPicksBatchHandler(pickStore)))))
+	http.HandleFunc("/insertPropPick", enableCORS(authenticate(rateLimit(propPickRateKey, insertPropPickHandler(pickStore)))))
+	http.HandleFunc("/api/v1/getPropPicksForUserAndEvent", enableCORS(authenticate(getPropPicksForUserAndEventHandler(pickStore))))
+	http.HandleFunc("/api/v1/submitConfidencePicks", enableCORS(authenticate(rateLimit(userRouteRateKey, submitConfidencePicksHandler(pickStore)))))
+	http.HandleFunc("/api/v1/getConfidenceStandings", enableCORS(getConfidenceStandingsHandler))
+	http.HandleFunc("/api/v1/getUserOddsStats", enableCORS(authenticate(getUserOddsStatsHandler)))
+	http.HandleFunc("/api/v1/getUserAnalytics", enableCORS(authenticate(getUserAnalyticsHandler)))
+	http.HandleFunc("/api/v1/getUserAchievements", enableCORS(authenticate(getUserAchievementsHandler)))
+	http.HandleFunc("/api/v1/getProbabilityStats", enableCORS(authenticate(getProbabilityStatsHandler)))
+	http.HandleFunc("/api/v1/getCalibration", enableCORS(authenticate(getCalibrationHandler)))
+	http.HandleFunc("/api/v1/exportPicks", enableCORS(authenticate(exportPicksHandler)))
+	http.HandleFunc("/api/v1/getPickHistoryForUser", enableCORS(authenticate(getPickHistoryForUserHandler)))
+	http.HandleFunc("/api/v1/getPickHistoryForMatchup", enableCORS(authenticate(getPickHistoryForMatchupHandler)))
+	http.HandleFunc("/api/v1/getLeaderboard", enableCORS(getLeaderboardHandler))
+	http.HandleFunc("/api/v1/getSeasons", enableCORS(getSeasonsHandler))
+	http.HandleFunc("/api/v1/getSeasonChampions", enableCORS(getSeasonChampionsHandler))
+	http.HandleFunc("/api/v1/createSeason", enableCORS(authenticate(createSeasonHandler)))
+	http.HandleFunc("/api/v1/closeSeason", enableCORS(authenticate(closeSeasonHandler)))
+	http.HandleFunc("/api/v1/createLeague", enableCORS(authenticate(createLeagueHandler)))
+	http.HandleFunc("/api/v1/getMyLeagues", enableCORS(authenticate(getMyLeaguesHandler)))
+	http.HandleFunc("/api/v1/createLeagueInvite", enableCORS(authenticate(createLeagueInviteHandler)))
+	http.HandleFunc("/api/v1/revokeLeagueInvite", enableCORS(authenticate(revokeLeagueInviteHandler)))
+	http.HandleFunc("/api/v1/joinLeague", enableCORS(authenticate(joinLeagueHandler)))
+	http.HandleFunc("/api/v1/leaveLeague", enableCORS(authenticate(leaveLeagueHandler)))
+	http.HandleFunc("/api/v1/setLeagueMemberRole", enableCORS(authenticate(setLeagueMemberRoleHandler)))
+	http.HandleFunc("/api/v1/removeLeagueMember", enableCORS(authenticate(removeLeagueMemberHandler)))
+	http.HandleFunc("/api/v1/getLeagueMembers", enableCORS(authenticate(getLeagueMembersHandler)))
+	http.HandleFunc("/api/v1/getLeagueLeaderboard", enableCORS(authenticate(getLeagueLeaderboardHandler)))
+	http.HandleFunc("/api/v1/createSurvivorContest", enableCORS(authenticate(createSurvivorContestHandler)))
+	http.HandleFunc("/api/v1/joinSurvivorContest", enableCORS(authenticate(joinSurvivorContestHandler)))
+	http.HandleFunc("/api/v1/submitSurvivorPick", enableCORS(authenticate(rateLimit(userRouteRateKey, submitSurvivorPickHandler(pickStore)))))
+	http.HandleFunc("/api/v1/getMySurvivorPicks", enableCORS(authenticate(getMySurvivorPicksHandler)))
+	http.HandleFunc("/api/v1/getSurvivorStandings", enableCORS(getSurvivorStandingsHandler))
+	http.HandleFunc("/api/v1/gradeMatchup", requireAPIKey(gradeMatchupHandler(pickStore)))
+	http.HandleFunc("/api/v1/cancelMatchup", requireAPIKey(cancelMatchupHandler(pickStore)))
+	http.HandleFunc("/api/v1/rebookMatchup", requireAPIKey(rebookMatchupHandler(pickStore)))
+	http.HandleFunc("/api/v1/setEventSchedule", requireAPIKey(setEventScheduleHandler))
 
 	port := getEnvWithFallback("PORT", "8080")
 	fmt.Printf("Server starting on :%s\n", port)
@@ -71,116 +192,819 @@ func handleRoot(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "Welcome to the Go backend!")
 }
 
-func getPicksForEventHandler(w http.ResponseWriter, r *http.Request) {
+// raw picks of an event, including who made them. admin only; the public gets getConsensusForEvent
+func getPicksForEventHandler(store db.PickStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+			return
+		}
+
+		authUserId, ok := authenticatedUserId(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !requireAdmin(w, authUserId) {
+			return
+		}
+
+		eventId := r.URL.Query().Get("eventId")
+		if eventId == "" {
+			http.Error(w, "Missing query parameter: eventId", http.StatusBadRequest)
+			return
+		}
+
+		q, err := parsePickQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page, err := store.GetPicksForEvent(eventId, q)
+		if errors.Is(err, db.ErrInvalidPickQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error retrieving picks for event: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			http.Error(w, "Error encoding picks to JSON", http.StatusInternalServerError)
+		}
+	}
+}
+
+func getPicksForUserAndEventHandler(store db.PickStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+			return
+		}
+
+		authUserId, ok := authenticatedUserId(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		userIdStr := r.URL.Query().Get("userId") //assign query parameter to userIdStr
+		eventId := r.URL.Query().Get("eventId")
+
+		if userIdStr == "" || eventId == "" {
+			http.Error(w, "Missing query parameters: userId and eventId are required", http.StatusBadRequest)
+			return
+		}
+
+		//convert userIdStr to int
+		userId, err := strconv.Atoi(userIdStr)
+		if err != nil {
+			http.Error(w, "Invalid userId: must be an integer", http.StatusBadRequest)
+			return
+		}
+
+		//picks of fights that haven't locked would give away a user's card, so only they and admins can see them
+		if userId != authUserId && !requireAdmin(w, authUserId) {
+			return
+		}
+
+		q, err := parsePickQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page, err := store.GetPicksForUserAndEvent(userId, eventId, q)
+		if errors.Is(err, db.ErrInvalidPickQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error retrieving picks for user and event: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			http.Error(w, "Error encoding picks to JSON", http.StatusInternalServerError)
+		}
+	}
+}
+
+// raw picks of a matchup, including who made them. admin only; the public gets getConsensusForMatchup
+func getPicksForMatchupHandler(store db.PickStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+			return
+		}
+
+		authUserId, ok := authenticatedUserId(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !requireAdmin(w, authUserId) {
+			return
+		}
+
+		matchupId := r.URL.Query().Get("matchupId")
+		if matchupId == "" {
+			http.Error(w, "Missing query parameter: matchupId", http.StatusBadRequest)
+			return
+		}
+
+		q, err := parsePickQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page, err := store.GetPicksForMatchup(matchupId, q)
+		if errors.Is(err, db.ErrInvalidPickQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error retrieving picks for matchup: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			http.Error(w, "Error encoding picks to JSON", http.StatusInternalServerError)
+		}
+	}
+}
+
+func insertPickHandler(store db.PickStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		authUserId, ok := authenticatedUserId(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		userId := strconv.Itoa(authUserId)
+
+		//userId is no longer needed, but older clients still send it. never let it differ from the token
+		if formUserId := r.FormValue("userId"); formUserId != "" && formUserId != userId {
+			log.Printf("Rejected pick: form userId %s does not match token userId %s", formUserId, userId)
+			http.Error(w, "userId does not match authenticated user", http.StatusForbidden)
+			return
+		}
+
+		matchupId := r.FormValue("matchupId")
+		eventId := r.FormValue("eventId")
+		selectionId := r.FormValue("selectionId")
+
+		//optional win probability of the selected fighter, e.g. 0.65
+		var probability *float64
+		if value := r.FormValue("probability"); value != "" {
+			p, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				http.Error(w, "Invalid probability: must be a number", http.StatusBadRequest)
+				return
+			}
+			probability = &p
+		}
+
+		//admins can fix picks after lock (e.g. a pick that failed to save before the card started)
+		overrideLock := false
+		if r.FormValue("override") == "true" {
+			isAdmin, err := db.IsAdmin(authUserId)
+			if err != nil {
+				log.Printf("Error checking admin role: %v", err)
+				http.Error(w, "Error inserting / updating pick", http.StatusInternalServerError)
+				return
+			}
+			if !isAdmin {
+				http.Error(w, "Only admins can override pick locks", http.StatusForbidden)
+				return
+			}
+			overrideLock = true
+		}
+
+		log.Printf("Attempting to upsert pick - userId: %s, matchupId: %s, eventId: %s, selectionId: %s",
+			userId, matchupId, eventId, selectionId)
+
+		meta := pickMetaFromRequest(r, db.PickSourceSingle)
+		meta.OverrideLock = overrideLock
+		if overrideLock {
+			meta.Source = db.PickSourceAdmin
+		}
+
+		err := store.UpsertPick(userId, matchupId, eventId, selectionId, probability, meta)
+		if err != nil {
+			log.Printf("Error occurred: %v", err)
+
+			if errors.Is(err, db.ErrPickLocked) {
+				http.Error(w, err.Error(), http.StatusLocked)
+			} else if errors.Is(err, db.ErrInvalidProbability) || errors.Is(err, db.ErrInvalidPick) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				log.Printf("Other error occurred: %v", err)
+				http.Error(w, "Error inserting / updating pick", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		fmt.Fprintf(w, "Successfully inserted pick!")
+	}
+}
+
+// submits every pick for an event in one request:
+// {"eventId": "...", "picks": [{"matchupId": "...", "selectionId": "..."}, ...]}
+// the batch is all or nothing; the response has a result per pick either way
+func insertPicksBatchHandler(store db.PickStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userId, _ := authenticatedUserId(r)
+
+		var req struct {
+			EventID string         `json:"eventId"`
+			Picks   []db.BatchPick `json:"picks"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if req.EventID == "" {
+			http.Error(w, "Missing field: eventId", http.StatusBadRequest)
+			return
+		}
+
+		results, err := store.UpsertPicksBatch(userId, req.EventID, req.Picks, pickMetaFromRequest(r, db.PickSourceBatch))
+		if err != nil {
+			log.Printf("Error saving pick batch for user %d: %v", userId, err)
+
+			switch {
+			case errors.Is(err, db.ErrInvalidBatch) && results != nil:
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"error":   err.Error(),
+					"results": results,
+				})
+			case errors.Is(err, db.ErrInvalidBatch):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, db.ErrPickLocked):
+				//a fight locked between validation and the write
+				http.Error(w, err.Error(), http.StatusLocked)
+			default:
+				http.Error(w, "Error saving pick batch", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"message": "Successfully saved picks!",
+			"results": results,
+		})
+	}
+}
+
+func getPropPicksForUserAndEventHandler(store db.PickStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+			return
+		}
+
+		authUserId, ok := authenticatedUserId(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		userIdStr := r.URL.Query().Get("userId")
+		eventId := r.URL.Query().Get("eventId")
+
+		if userIdStr == "" || eventId == "" {
+			http.Error(w, "Missing query parameters: userId and eventId are required", http.StatusBadRequest)
+			return
+		}
+
+		userId, err := strconv.Atoi(userIdStr)
+		if err != nil {
+			http.Error(w, "Invalid userId: must be an integer", http.StatusBadRequest)
+			return
+		}
+
+		//same as moneyline picks, only the user and admins can see them
+		if userId != authUserId && !requireAdmin(w, authUserId) {
+			return
+		}
+
+		props, err := store.GetPropPicksForUserAndEvent(userId, eventId)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error retrieving prop picks for user and event: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(props); err != nil {
+			http.Error(w, "Error encoding prop picks to JSON", http.StatusInternalServerError)
+		}
+	}
+}
+
+// method (KO/TKO, SUB, DEC) and optional round prop for a matchup, graded alongside the moneyline pick
+func insertPropPickHandler(store db.PickStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userId, ok := authenticatedUserId(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		matchupId := r.FormValue("matchupId")
+		eventId := r.FormValue("eventId")
+		method := r.FormValue("method")
+		if matchupId == "" || eventId == "" || method == "" {
+			http.Error(w, "Missing fields: matchupId, eventId and method are required", http.StatusBadRequest)
+			return
+		}
+
+		var round *int
+		if roundStr := r.FormValue("round"); roundStr != "" {
+			parsed, err := strconv.Atoi(roundStr)
+			if err != nil {
+				http.Error(w, "Invalid round: must be an integer", http.StatusBadRequest)
+				return
+			}
+			round = &parsed
+		}
+
+		err := store.UpsertPropPick(userId, matchupId, eventId, method, round, false)
+		if err != nil {
+			log.Printf("Error upserting prop pick: %v", err)
+
+			switch {
+			case errors.Is(err, db.ErrPickLocked):
+				http.Error(w, err.Error(), http.StatusLocked)
+			case errors.Is(err, db.ErrInvalidProp), errors.Is(err, db.ErrInvalidPick):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				http.Error(w, "Error inserting / updating prop pick", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		fmt.Fprintf(w, "Successfully inserted prop pick!")
+	}
+}
+
+// units won, ROI and closing line value of a user's graded picks. users can see their own, admins anyone's
+func getUserOddsStatsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
 		return
 	}
 
-	eventId := r.URL.Query().Get("eventId")
-	if eventId == "" {
-		http.Error(w, "Missing query parameter: eventId", http.StatusBadRequest)
+	authUserId, ok := authenticatedUserId(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	picks, err := db.GetPicksForEvent(eventId)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Error retrieving picks for event: %v", err), http.StatusInternalServerError)
+	userId := authUserId
+	if param := r.URL.Query().Get("userId"); param != "" {
+		var err error
+		userId, err = strconv.Atoi(param)
+		if err != nil {
+			http.Error(w, "Invalid userId: must be an integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if userId != authUserId && !requireAdmin(w, authUserId) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(picks); err != nil {
-		http.Error(w, "Error encoding picks to JSON", http.StatusInternalServerError)
+	stats, err := db.GetUserOddsStats(userId)
+	if err != nil {
+		log.Printf("Error retrieving odds stats for user %d: %v", userId, err)
+		http.Error(w, "Error retrieving odds stats", http.StatusInternalServerError)
+		return
 	}
+
+	writeJSON(w, stats)
 }
 
-func getPicksForUserAndEventHandler(w http.ResponseWriter, r *http.Request) {
+// accuracy of a user's graded picks by weight class, card section, favorite / underdog and finish method,
+// with streaks and a monthly trend. users can see their own, admins anyone's
+func getUserAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
 		return
 	}
 
-	userIdStr := r.URL.Query().Get("userId") //assign query parameter to userIdStr
-	eventId := r.URL.Query().Get("eventId")
+	authUserId, ok := authenticatedUserId(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 
-	if userIdStr == "" || eventId == "" {
-		http.Error(w, "Missing query parameters: userId and eventId are required", http.StatusBadRequest)
+	userId := authUserId
+	if param := r.URL.Query().Get("userId"); param != "" {
+		var err error
+		userId, err = strconv.Atoi(param)
+		if err != nil {
+			http.Error(w, "Invalid userId: must be an integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if userId != authUserId && !requireAdmin(w, authUserId) {
 		return
 	}
 
-	//convert userIdStr to int
-	userId, err := strconv.Atoi(userIdStr)
+	analytics, err := db.GetUserAnalytics(userId)
 	if err != nil {
-		http.Error(w, "Invalid userId: must be an integer", http.StatusBadRequest)
+		log.Printf("Error retrieving analytics for user %d: %v", userId, err)
+		http.Error(w, "Error retrieving analytics", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, analytics)
+}
+
+// Every badge with whether the user earned it, for the profile page. users can see their own, admins anyone's
+func getUserAchievementsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
 		return
 	}
 
-	picks, err := db.GetPicksForUserAndEvent(userId, eventId)
+	authUserId, ok := authenticatedUserId(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userId := authUserId
+	if param := r.URL.Query().Get("userId"); param != "" {
+		var err error
+		userId, err = strconv.Atoi(param)
+		if err != nil {
+			http.Error(w, "Invalid userId: must be an integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if userId != authUserId && !requireAdmin(w, authUserId) {
+		return
+	}
+
+	achievements, err := db.GetUserAchievements(userId)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error retrieving picks for user and event: %v", err), http.StatusInternalServerError)
+		log.Printf("Error retrieving achievements for user %d: %v", userId, err)
+		http.Error(w, "Error retrieving achievements", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(picks); err != nil {
-		http.Error(w, "Error encoding picks to JSON", http.StatusInternalServerError)
+	writeJSON(w, achievements)
+}
+
+// Brier score, log-loss and accuracy of a user's graded probability picks. users can see their own, admins anyone's
+func getProbabilityStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authUserId, ok := authenticatedUserId(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userId := authUserId
+	if param := r.URL.Query().Get("userId"); param != "" {
+		var err error
+		userId, err = strconv.Atoi(param)
+		if err != nil || userId <= 0 {
+			http.Error(w, "Invalid userId: must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if userId != authUserId && !requireAdmin(w, authUserId) {
+		return
 	}
+
+	stats, err := db.GetProbabilityStats(userId)
+	if err != nil {
+		log.Printf("Error retrieving probability stats for user %d: %v", userId, err)
+		http.Error(w, "Error retrieving probability stats", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, stats)
 }
 
-func getPicksForMatchupHandler(w http.ResponseWriter, r *http.Request) {
+// calibration curve of a user's probability picks, or of every user's when userId is left out. users can see
+// their own curve and the community one, admins anyone's
+func getCalibrationHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
 		return
 	}
 
-	matchupId := r.URL.Query().Get("matchupId")
-	if matchupId == "" {
-		http.Error(w, "Missing query parameter: matchupId", http.StatusBadRequest)
+	authUserId, ok := authenticatedUserId(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	picks, err := db.GetPicksForMatchup(matchupId)
+	userId := 0
+	if param := r.URL.Query().Get("userId"); param != "" {
+		var err error
+		userId, err = strconv.Atoi(param)
+		if err != nil || userId <= 0 {
+			http.Error(w, "Invalid userId: must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		if userId != authUserId && !requireAdmin(w, authUserId) {
+			return
+		}
+	}
+
+	stats, err := db.GetProbabilityStats(userId)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error retrieving picks for matchup: %v", err), http.StatusInternalServerError)
+		log.Printf("Error retrieving calibration for user %d: %v", userId, err)
+		http.Error(w, "Error retrieving calibration", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(picks); err != nil {
-		http.Error(w, "Error encoding picks to JSON", http.StatusInternalServerError)
+	writeJSON(w, stats.Curve)
+}
+
+// grades the picks for a matchup, regrading them if the result changed. winner and result take the event
+// scraper's Winner and Result values as-is, so "Draw/No Contest" pushes (draw) or voids (no contest) picks
+// and e.g. "FinalKO/TKOR1, 0:21" grades props; otherwise winnerId must be the winning fighter's id
+func gradeMatchupHandler(store db.PickStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			EventID   string `json:"eventId"`
+			MatchupID string `json:"matchupId"`
+			WinnerID  string `json:"winnerId"`
+			Winner    string `json:"winner"`
+			Result    string `json:"result"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if req.EventID == "" || req.MatchupID == "" {
+			http.Error(w, "Missing fields: eventId and matchupId are required", http.StatusBadRequest)
+			return
+		}
+
+		winnerId := req.WinnerID
+		if strings.EqualFold(strings.TrimSpace(req.Winner), db.DrawOrNoContest) {
+			winnerId = ""
+		} else if winnerId == "" {
+			http.Error(w, "Missing field: winnerId is required unless winner is \"Draw/No Contest\"", http.StatusBadRequest)
+			return
+		}
+
+		summary, err := store.UpdateMatchupPickResults(winnerId, req.EventID, req.MatchupID, req.Result)
+		if err != nil {
+			log.Printf("Error grading matchup %s: %v", req.MatchupID, err)
+			http.Error(w, "Error grading matchup", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(summary); err != nil {
+			http.Error(w, "Error encoding grading summary to JSON", http.StatusInternalServerError)
+		}
+	}
+}
+
+// cancels a scrapped bout: its picks and props are cancelled and never count
+func cancelMatchupHandler(store db.PickStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			EventID   string `json:"eventId"`
+			MatchupID string `json:"matchupId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if req.EventID == "" || req.MatchupID == "" {
+			http.Error(w, "Missing fields: eventId and matchupId are required", http.StatusBadRequest)
+			return
+		}
+
+		summary, err := store.CancelMatchup(req.EventID, req.MatchupID)
+		if err != nil {
+			log.Printf("Error cancelling matchup %s: %v", req.MatchupID, err)
+			http.Error(w, "Error cancelling matchup", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, summary)
+	}
+}
+
+// handles a replacement opponent: picks on the withdrawn fighter are cancelled and the rest move to
+// newMatchupId, which can be left out when the scraper kept the same matchup id
+func rebookMatchupHandler(store db.PickStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			EventID            string `json:"eventId"`
+			MatchupID          string `json:"matchupId"`
+			NewMatchupID       string `json:"newMatchupId"`
+			WithdrawnFighterID string `json:"withdrawnFighterId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if req.EventID == "" || req.MatchupID == "" || req.WithdrawnFighterID == "" {
+			http.Error(w, "Missing fields: eventId, matchupId and withdrawnFighterId are required", http.StatusBadRequest)
+			return
+		}
+
+		summary, err := store.RebookMatchup(req.EventID, req.MatchupID, req.NewMatchupID, req.WithdrawnFighterID)
+		if err != nil {
+			if errors.Is(err, db.ErrMatchupGraded) {
+				http.Error(w, "Matchup already has a result", http.StatusConflict)
+				return
+			}
+			log.Printf("Error rebooking matchup %s: %v", req.MatchupID, err)
+			http.Error(w, "Error rebooking matchup", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, summary)
 	}
 }
 
-func insertPickHandler(w http.ResponseWriter, r *http.Request) {
+// stores start times for an event's matchups so picks lock on time. the body mirrors the event scraper's
+// Event (Date, MainCardTime, PrelimsTime, EarlyPrelimsTime, CardType) plus the live-stats StartTimestamp per fight.
+// weightClass is optional and only used for pick analytics
+func setEventScheduleHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
 		return
 	}
 
-	userId := r.FormValue("userId")
-	matchupId := r.FormValue("matchupId")
-	eventId := r.FormValue("eventId")
-	selectionId := r.FormValue("selectionId")
+	var req struct {
+		EventID          string `json:"eventId"`
+		EventName        string `json:"eventName"`
+		Date             string `json:"date"`
+		MainCardTime     string `json:"mainCardTime"`
+		PrelimsTime      string `json:"prelimsTime"`
+		EarlyPrelimsTime string `json:"earlyPrelimsTime"`
+		Matchups         []struct {
+			MatchupID       string `json:"matchupId"`
+			CardType        string `json:"cardType"`
+			StartTimestamp  int64  `json:"startTimestamp"`
+			ScheduledRounds int    `json:"scheduledRounds"`
+			WeightClass     string `json:"weightClass"`
+			Fighter1ID      string `json:"fighter1Id"`
+			Fighter1Name    string `json:"fighter1Name"`
+			Fighter2ID      string `json:"fighter2Id"`
+			Fighter2Name    string `json:"fighter2Name"`
+		} `json:"matchups"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
 
-	log.Printf("Attempting to upsert pick - userId: %s, matchupId: %s, eventId: %s, selectionId: %s",
-		userId, matchupId, eventId, selectionId)
+	if req.EventID == "" || len(req.Matchups) == 0 {
+		http.Error(w, "Missing fields: eventId and matchups are required", http.StatusBadRequest)
+		return
+	}
 
-	err := db.UpsertPick(userId, matchupId, eventId, selectionId)
-	if err != nil {
-		log.Printf("Error occurred: %v", err)
+	//the event date places its picks in a season
+	var eventDate *time.Time
+	if req.Date != "" {
+		date, err := db.ParseEventDate(req.Date)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		eventDate = &date
+	}
+
+	//start time of each card section. sections without a time are left out
+	cardStarts := make(map[string]time.Time)
+	var earliest *time.Time
+	for cardType, cardTime := range map[string]string{
+		db.CardTypeMainCard:     req.MainCardTime,
+		db.CardTypePrelims:      req.PrelimsTime,
+		db.CardTypeEarlyPrelims: req.EarlyPrelimsTime,
+	} {
+		if cardTime == "" {
+			continue
+		}
+		start, err := db.ParseCardStartTime(req.Date, cardTime)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid %s start time: %v", cardType, err), http.StatusBadRequest)
+			return
+		}
+		cardStarts[cardType] = start
+		if earliest == nil || start.Before(*earliest) {
+			earliest = &start
+		}
+	}
+
+	schedules := make([]db.MatchupSchedule, 0, len(req.Matchups))
+	for _, m := range req.Matchups {
+		if m.MatchupID == "" {
+			http.Error(w, "Missing field: matchupId is required for every matchup", http.StatusBadRequest)
+			return
+		}
 
-		if strings.Contains(err.Error(), "rate limit exceeded") {
-			log.Printf("Rate limit error detected")
-			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		if m.ScheduledRounds < 0 || m.ScheduledRounds > db.MaxScheduledRounds {
+			http.Error(w, fmt.Sprintf("Invalid scheduledRounds for matchup %s", m.MatchupID), http.StatusBadRequest)
+			return
+		}
+
+		schedule := db.MatchupSchedule{MatchupID: m.MatchupID, EventID: req.EventID, CardType: m.CardType,
+			ScheduledRounds: m.ScheduledRounds, WeightClass: strings.TrimSpace(m.WeightClass), EventName: strings.TrimSpace(req.EventName),
+			Fighter1ID: m.Fighter1ID, Fighter1Name: strings.TrimSpace(m.Fighter1Name), Fighter2ID: m.Fighter2ID, Fighter2Name: strings.TrimSpace(m.Fighter2Name)}
+		if start, ok := cardStarts[strings.TrimSpace(m.CardType)]; ok {
+			schedule.CardStartsAt = &start
 		} else {
-			log.Printf("Other error occurred: %v", err)
-			http.Error(w, "Error inserting / updating pick", http.StatusInternalServerError)
+			//unknown section: lock with the first section of the card to be safe
+			schedule.CardStartsAt = earliest
+		}
+		if m.StartTimestamp > 0 {
+			fightStart := time.Unix(m.StartTimestamp, 0)
+			schedule.FightStartsAt = &fightStart
 		}
+		schedules = append(schedules, schedule)
+	}
+
+	if err := db.UpsertMatchupSchedules(schedules); err != nil {
+		log.Printf("Error storing schedule for event %s: %v", req.EventID, err)
+		http.Error(w, "Error storing event schedule", http.StatusInternalServerError)
 		return
 	}
 
-	fmt.Fprintf(w, "Successfully inserted pick!")
+	if eventDate != nil {
+		if err := db.SetEventDate(req.EventID, *eventDate); err != nil {
+			log.Printf("Error storing date for event %s: %v", req.EventID, err)
+			http.Error(w, "Error storing event schedule", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(schedules); err != nil {
+		http.Error(w, "Error encoding schedule to JSON", http.StatusInternalServerError)
+	}
+}
+
+// writeJSON encodes v as the JSON response body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Error encoding response to JSON", http.StatusInternalServerError)
+	}
 }
 
 func getEnvWithFallback(key, fallback string) string {
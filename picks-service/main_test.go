@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"picks-service/db"
+	"picks-service/events"
+	"shared/auth"
+)
+
+func TestMain(m *testing.M) {
+	jwtKey = []byte("test-secret")
+	os.Exit(m.Run())
+}
+
+// testCards reports e1's card the way the scraper service would
+type testCards struct{}
+
+func (testCards) GetEvent(ctx context.Context, eventID string) (events.Event, error) {
+	if eventID != "e1" {
+		return events.Event{}, events.ErrEventNotFound
+	}
+	return events.Event{EventID: "e1", Matchups: []events.Matchup{
+		{MatchupID: "m1", Fighter1ID: "f1", Fighter2ID: "f2"},
+		{MatchupID: "m2", Fighter1ID: "f3", Fighter2ID: "f4"},
+	}}, nil
+}
+
+// newHandlerTestStore returns a store with m1 open and m2 locked on e1's card
+func newHandlerTestStore(t *testing.T) *db.MemoryPickStore {
+	t.Helper()
+	store := db.NewMemoryPickStore()
+	store.Events = testCards{}
+	now := time.Now()
+	open, locked := now.Add(time.Hour), now.Add(-time.Hour)
+	store.SetSchedule(db.MatchupSchedule{MatchupID: "m1", EventID: "e1", CardStartsAt: &open, Fighter1ID: "f1", Fighter2ID: "f2", ScheduledRounds: 3})
+	store.SetSchedule(db.MatchupSchedule{MatchupID: "m2", EventID: "e1", CardStartsAt: &locked, Fighter1ID: "f3", Fighter2ID: "f4", ScheduledRounds: 3})
+	return store
+}
+
+// serve runs the handler behind authenticate with a token for userId, or without a token when userId is empty.
+// A body starting with { is sent as JSON, anything else as a form
+func serve(t *testing.T, handler http.HandlerFunc, method string, target string, body string, userId string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if strings.HasPrefix(body, "{") {
+		r.Header.Set("Content-Type", "application/json")
+	} else if body != "" {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if userId != "" {
+		token, err := auth.NewToken("tester", userId, time.Now().Add(time.Hour), jwtKey)
+		if err != nil {
+			t.Fatalf("NewToken: %v", err)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	authenticate(handler)(w, r)
+	return w
+}
+
+func form(values map[string]string) string {
+	v := url.Values{}
+	for key, value := range values {
+		v.Set(key, value)
+	}
+	return v.Encode()
+}
+
+func TestInsertPickHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		userId string
+		body   string
+		want   int
+		stored bool
+	}{
+		{name: "pick", method: http.MethodPost, userId: "1", body: form(map[string]string{"matchupId": "m1", "eventId": "e1", "selectionId": "f1"}), want: http.StatusOK, stored: true},
+		{name: "probability pick", method: http.MethodPost, userId: "1", body: form(map[string]string{"matchupId": "m1", "eventId": "e1", "selectionId": "f1", "probability": "0.7"}), want: http.StatusOK, stored: true},
+		{name: "matching legacy userId", method: http.MethodPost, userId: "1", body: form(map[string]string{"userId": "1", "matchupId": "m1", "eventId": "e1", "selectionId": "f1"}), want: http.StatusOK, stored: true},
+		{name: "someone else's userId", method: http.MethodPost, userId: "1", body: form(map[string]string{"userId": "2", "matchupId": "m1", "eventId": "e1", "selectionId": "f1"}), want: http.StatusForbidden},
+		{name: "locked fight", method: http.MethodPost, userId: "1", body: form(map[string]string{"matchupId": "m2", "eventId": "e1", "selectionId": "f3"}), want: http.StatusLocked},
+		{name: "probability out of range", method: http.MethodPost, userId: "1", body: form(map[string]string{"matchupId": "m1", "eventId": "e1", "selectionId": "f1", "probability": "1.5"}), want: http.StatusBadRequest},
+		{name: "probability not a number", method: http.MethodPost, userId: "1", body: form(map[string]string{"matchupId": "m1", "eventId": "e1", "selectionId": "f1", "probability": "high"}), want: http.StatusBadRequest},
+		{name: "no token", method: http.MethodPost, body: form(map[string]string{"matchupId": "m1", "eventId": "e1", "selectionId": "f1"}), want: http.StatusUnauthorized},
+		{name: "wrong method", method: http.MethodGet, userId: "1", want: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newHandlerTestStore(t)
+			w := serve(t, insertPickHandler(store), tt.method, "/insertPick", tt.body, tt.userId)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+
+			page, _ := store.GetPicksForUserAndEvent(1, "e1", db.PickQuery{})
+			if stored := page.Total > 0; stored != tt.stored {
+				t.Errorf("stored = %v, want %v", stored, tt.stored)
+			}
+		})
+	}
+}
+
+func TestInsertPicksBatchHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "full card", body: `{"eventId": "e1", "picks": [{"matchupId": "m1", "selectionId": "f2"}]}`, want: http.StatusOK},
+		{name: "locked fight", body: `{"eventId": "e1", "picks": [{"matchupId": "m1", "selectionId": "f2"}, {"matchupId": "m2", "selectionId": "f3"}]}`, want: http.StatusUnprocessableEntity},
+		{name: "fighter not in the fight", body: `{"eventId": "e1", "picks": [{"matchupId": "m1", "selectionId": "f3"}]}`, want: http.StatusUnprocessableEntity},
+		{name: "missing eventId", body: `{"picks": []}`, want: http.StatusBadRequest},
+		{name: "malformed body", body: `{"eventId": `, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newHandlerTestStore(t)
+			w := serve(t, insertPicksBatchHandler(store), http.MethodPost, "/api/v1/insertPicksBatch", tt.body, "1")
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+
+			//the batch is all or nothing
+			page, _ := store.GetPicksForUserAndEvent(1, "e1", db.PickQuery{})
+			if want := tt.want == http.StatusOK; (page.Total > 0) != want {
+				t.Errorf("stored %d picks, want stored = %v", page.Total, want)
+			}
+		})
+	}
+}
+
+func TestInsertPropPickHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "method and round", body: form(map[string]string{"matchupId": "m1", "eventId": "e1", "method": "ko", "round": "2"}), want: http.StatusOK},
+		{name: "decision", body: form(map[string]string{"matchupId": "m1", "eventId": "e1", "method": "decision"}), want: http.StatusOK},
+		{name: "decision in a round", body: form(map[string]string{"matchupId": "m1", "eventId": "e1", "method": "DEC", "round": "2"}), want: http.StatusBadRequest},
+		{name: "round past the schedule", body: form(map[string]string{"matchupId": "m1", "eventId": "e1", "method": "SUB", "round": "4"}), want: http.StatusBadRequest},
+		{name: "unknown method", body: form(map[string]string{"matchupId": "m1", "eventId": "e1", "method": "doctor stoppage"}), want: http.StatusBadRequest},
+		{name: "locked fight", body: form(map[string]string{"matchupId": "m2", "eventId": "e1", "method": "SUB"}), want: http.StatusLocked},
+		{name: "missing method", body: form(map[string]string{"matchupId": "m1", "eventId": "e1"}), want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newHandlerTestStore(t)
+			w := serve(t, insertPropPickHandler(store), http.MethodPost, "/insertPropPick", tt.body, "1")
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetPicksForUserAndEventHandler(t *testing.T) {
+	store := newHandlerTestStore(t)
+	if err := store.UpsertPick("1", "m1", "e1", "f1", nil, db.PickMeta{}); err != nil {
+		t.Fatalf("UpsertPick: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		want   int
+		total  int
+	}{
+		{name: "own picks", target: "/api/v1/getPicksForUserAndEvent?userId=1&eventId=e1", want: http.StatusOK, total: 1},
+		{name: "another event", target: "/api/v1/getPicksForUserAndEvent?userId=1&eventId=e2", want: http.StatusOK},
+		{name: "missing eventId", target: "/api/v1/getPicksForUserAndEvent?userId=1", want: http.StatusBadRequest},
+		{name: "userId not a number", target: "/api/v1/getPicksForUserAndEvent?userId=me&eventId=e1", want: http.StatusBadRequest},
+		{name: "bad status filter", target: "/api/v1/getPicksForUserAndEvent?userId=1&eventId=e1&status=lost", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, getPicksForUserAndEventHandler(store), http.MethodGet, tt.target, "", "1")
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var page db.PickPage
+			if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+				t.Fatalf("decoding page: %v", err)
+			}
+			if page.Total != tt.total || len(page.Data) != tt.total {
+				t.Errorf("page has %d of %d picks, want %d", len(page.Data), page.Total, tt.total)
+			}
+		})
+	}
+}
+
+func TestGradeMatchupHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+		// pick result of user 1 (f1) and user 2 (f2) after grading
+		results [2]string
+	}{
+		{name: "winner", body: `{"eventId": "e1", "matchupId": "m1", "winnerId": "f1", "result": "FinalKO/TKOR1, 0:21"}`, want: http.StatusOK,
+			results: [2]string{db.PickResultCorrect, db.PickResultIncorrect}},
+		{name: "draw", body: `{"eventId": "e1", "matchupId": "m1", "winner": "Draw/No Contest", "result": "FinalDrawR3, 5:00"}`, want: http.StatusOK,
+			results: [2]string{db.PickResultPush, db.PickResultPush}},
+		{name: "no contest", body: `{"eventId": "e1", "matchupId": "m1", "winner": "draw/no contest", "result": "FinalNCR1, 2:11"}`, want: http.StatusOK,
+			results: [2]string{db.PickResultVoid, db.PickResultVoid}},
+		{name: "missing winner", body: `{"eventId": "e1", "matchupId": "m1"}`, want: http.StatusBadRequest,
+			results: [2]string{db.PickResultPending, db.PickResultPending}},
+		{name: "missing matchup", body: `{"eventId": "e1", "winnerId": "f1"}`, want: http.StatusBadRequest,
+			results: [2]string{db.PickResultPending, db.PickResultPending}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newHandlerTestStore(t)
+			for userId, selection := range map[string]string{"1": "f1", "2": "f2"} {
+				if err := store.UpsertPick(userId, "m1", "e1", selection, nil, db.PickMeta{}); err != nil {
+					t.Fatalf("UpsertPick: %v", err)
+				}
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/gradeMatchup", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			gradeMatchupHandler(store)(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+
+			for i, want := range tt.results {
+				page, _ := store.GetPicksForUserAndEvent(i+1, "e1", db.PickQuery{})
+				if len(page.Data) != 1 || page.Data[0].PickResult != want {
+					t.Errorf("user %d picks = %+v, want one %q", i+1, page.Data, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCancelAndRebookMatchupHandlers(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(store db.PickStore) http.HandlerFunc
+		body    string
+		want    int
+		// pick result of user 1 (f1) and user 2 (f2) on matchup
+		matchup string
+		results [2]string
+	}{
+		{name: "cancel", handler: cancelMatchupHandler, body: `{"eventId": "e1", "matchupId": "m1"}`, want: http.StatusOK,
+			matchup: "m1", results: [2]string{db.PickResultCancelled, db.PickResultCancelled}},
+		{name: "rebook", handler: rebookMatchupHandler, body: `{"eventId": "e1", "matchupId": "m1", "newMatchupId": "m3", "withdrawnFighterId": "f2"}`, want: http.StatusOK,
+			matchup: "m3", results: [2]string{db.PickResultPending, ""}},
+		{name: "rebook without the withdrawn fighter", handler: rebookMatchupHandler, body: `{"eventId": "e1", "matchupId": "m1"}`, want: http.StatusBadRequest,
+			matchup: "m1", results: [2]string{db.PickResultPending, db.PickResultPending}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newHandlerTestStore(t)
+			for userId, selection := range map[string]string{"1": "f1", "2": "f2"} {
+				if err := store.UpsertPick(userId, "m1", "e1", selection, nil, db.PickMeta{}); err != nil {
+					t.Fatalf("UpsertPick: %v", err)
+				}
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/matchup", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			tt.handler(store)(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+
+			for i, want := range tt.results {
+				page, _ := store.GetPicksForMatchup(tt.matchup, db.PickQuery{})
+				got := ""
+				for _, p := range page.Data {
+					if p.UserID == i+1 {
+						got = p.PickResult
+					}
+				}
+				if got != want {
+					t.Errorf("user %d pick on %s = %q, want %q", i+1, tt.matchup, got, want)
+				}
+			}
+		})
+	}
+}
+
+// only the paths that stop before the database: the stats handlers need a token and a well-formed userId
+func TestStatsHandlersAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		target  string
+		userId  string
+		want    int
+	}{
+		{name: "odds stats without a token", handler: getUserOddsStatsHandler, target: "/api/v1/getUserOddsStats?userId=1", want: http.StatusUnauthorized},
+		{name: "odds stats with a bad userId", handler: getUserOddsStatsHandler, target: "/api/v1/getUserOddsStats?userId=x", userId: "1", want: http.StatusBadRequest},
+		{name: "analytics without a token", handler: getUserAnalyticsHandler, target: "/api/v1/getUserAnalytics?userId=1", want: http.StatusUnauthorized},
+		{name: "analytics with a bad userId", handler: getUserAnalyticsHandler, target: "/api/v1/getUserAnalytics?userId=x", userId: "1", want: http.StatusBadRequest},
+		{name: "achievements without a token", handler: getUserAchievementsHandler, target: "/api/v1/getUserAchievements?userId=1", want: http.StatusUnauthorized},
+		{name: "achievements with a bad userId", handler: getUserAchievementsHandler, target: "/api/v1/getUserAchievements?userId=x", userId: "1", want: http.StatusBadRequest},
+		{name: "probability stats without a token", handler: getProbabilityStatsHandler, target: "/api/v1/getProbabilityStats?userId=1", want: http.StatusUnauthorized},
+		{name: "probability stats with a bad userId", handler: getProbabilityStatsHandler, target: "/api/v1/getProbabilityStats?userId=0", userId: "1", want: http.StatusBadRequest},
+		{name: "calibration without a token", handler: getCalibrationHandler, target: "/api/v1/getCalibration", want: http.StatusUnauthorized},
+		{name: "calibration with a bad userId", handler: getCalibrationHandler, target: "/api/v1/getCalibration?userId=x", userId: "1", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, tt.handler, http.MethodGet, tt.target, "", tt.userId)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// notify delivers pick reminders to users by email, webhook or the log
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Reminder tells a user they still have fights to pick before an event locks
+type Reminder struct {
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"-"`
+	EventID   string    `json:"event_id"`
+	EventName string    `json:"event_name,omitempty"`
+	LocksAt   time.Time `json:"locks_at"`
+	Picked    int       `json:"picked"`
+	Matchups  int       `json:"matchups"`
+}
+
+// Missing is how many fights of the card the user hasn't picked yet
+func (r Reminder) Missing() int {
+	return r.Matchups - r.Picked
+}
+
+func (r Reminder) event() string {
+	if r.EventName != "" {
+		return r.EventName
+	}
+	return "the upcoming event"
+}
+
+// Notifier is anything that can deliver a reminder
+type Notifier interface {
+	Notify(ctx context.Context, reminder Reminder) error
+}
+
+// LogNotifier only logs reminders, for local runs
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, r Reminder) error {
+	log.Printf("Reminder for user %d (%s): %d of %d fights left to pick for %s, picks lock at %s",
+		r.UserID, r.Username, r.Missing(), r.Matchups, r.event(), r.LocksAt.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// WebhookNotifier POSTs each reminder as JSON to a URL, e.g. a push notification relay.
+// When Secret is set it is sent in the X-Webhook-Secret header
+type WebhookNotifier struct {
+	URL    string
+	Secret string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string, secret string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Secret: secret, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, r Reminder) error {
+	body, err := json.Marshal(struct {
+		Reminder
+		Missing int `json:"missing"`
+	}{r, r.Missing()})
+	if err != nil {
+		return fmt.Errorf("unable to encode reminder: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.Secret != "" {
+		req.Header.Set("X-Webhook-Secret", n.Secret)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to call reminder webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("reminder webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// EmailNotifier sends reminders over SMTP. Username and Password are optional for relays that don't need auth
+type EmailNotifier struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, r Reminder) error {
+	if r.Email == "" {
+		return errors.New("user has no email address")
+	}
+
+	subject := fmt.Sprintf("%d fights left to pick for %s", r.Missing(), r.event())
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\r\n\r\n", r.Username)
+	fmt.Fprintf(&body, "You've picked %d of %d fights for %s. Picks lock at %s.\r\n",
+		r.Picked, r.Matchups, r.event(), r.LocksAt.UTC().Format("Mon Jan 2 15:04 MST"))
+
+	//names come from scraped data, so keep them from adding headers
+	header := strings.NewReplacer("\r", "", "\n", "")
+	message := "From: " + n.From + "\r\n" +
+		"To: " + header.Replace(r.Email) + "\r\n" +
+		"Subject: " + header.Replace(subject) + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body.String()
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+	if err := smtp.SendMail(net.JoinHostPort(n.Host, n.Port), auth, n.From, []string{r.Email}, []byte(message)); err != nil {
+		return fmt.Errorf("unable to email reminder to user %d: %w", r.UserID, err)
+	}
+	return nil
+}
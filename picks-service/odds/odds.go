@@ -0,0 +1,105 @@
+// odds provides moneyline odds for matchups and the American odds math used to score picks
+package odds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+var ErrOddsNotFound = errors.New("no odds for matchup")
+
+// MatchupOdds holds the current American moneyline for each fighter of a matchup, keyed by fighter id
+type MatchupOdds struct {
+	MatchupID string         `json:"matchup_id"`
+	Fighters  map[string]int `json:"fighters"`
+}
+
+// Source is anything that can quote odds for a matchup
+type Source interface {
+	GetMatchupOdds(ctx context.Context, matchupID string) (MatchupOdds, error)
+}
+
+// NoSource is used when no odds provider is configured. Every lookup returns ErrOddsNotFound
+type NoSource struct{}
+
+func (NoSource) GetMatchupOdds(ctx context.Context, matchupID string) (MatchupOdds, error) {
+	return MatchupOdds{}, ErrOddsNotFound
+}
+
+// JSONFileSource serves odds from a local JSON file of the form
+//
+//	{"<matchupId>": {"<fighterId>": -150, "<fighterId>": 130}}
+//
+// which makes it usable as a fixture in tests and for local runs
+type JSONFileSource struct {
+	path string
+	mu   sync.RWMutex
+	odds map[string]map[string]int
+}
+
+func NewJSONFileSource(path string) (*JSONFileSource, error) {
+	s := &JSONFileSource{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload re-reads the file so odds can be updated without a restart
+func (s *JSONFileSource) Reload() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("unable to read odds file %s: %w", s.path, err)
+	}
+
+	var parsed map[string]map[string]int
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return fmt.Errorf("unable to parse odds file %s: %w", s.path, err)
+	}
+
+	s.mu.Lock()
+	s.odds = parsed
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *JSONFileSource) GetMatchupOdds(ctx context.Context, matchupID string) (MatchupOdds, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fighters, ok := s.odds[matchupID]
+	if !ok || len(fighters) == 0 {
+		return MatchupOdds{}, ErrOddsNotFound
+	}
+
+	copied := make(map[string]int, len(fighters))
+	for id, american := range fighters {
+		copied[id] = american
+	}
+	return MatchupOdds{MatchupID: matchupID, Fighters: copied}, nil
+}
+
+// Valid reports whether american is a real American moneyline (at least +100 or at most -100)
+func Valid(american int) bool {
+	return american >= 100 || american <= -100
+}
+
+// Payout returns the profit in units of a winning 1 unit bet: +150 pays 1.5, -200 pays 0.5
+func Payout(american int) float64 {
+	if american > 0 {
+		return float64(american) / 100
+	}
+	return 100 / float64(-american)
+}
+
+// ImpliedProbability converts American odds to the bookmaker's implied win probability
+func ImpliedProbability(american int) float64 {
+	if american > 0 {
+		return 100 / float64(american+100)
+	}
+	return float64(-american) / float64(-american+100)
+}
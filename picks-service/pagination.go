@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"picks-service/db"
+)
+
+// parsePickQuery reads the paging, filter and sort parameters shared by the pick listing endpoints:
+//
+//	status=correct,incorrect  from=2024-01-01  to=2024-06-30  sort=created_at  order=desc  limit=50  cursor=...
+//
+// from and to take a date or an RFC 3339 time; a date in to includes that whole day
+func parsePickQuery(r *http.Request) (db.PickQuery, error) {
+	params := r.URL.Query()
+	q := db.PickQuery{Sort: params.Get("sort"), Cursor: params.Get("cursor")}
+
+	if status := params.Get("status"); status != "" {
+		for _, result := range strings.Split(status, ",") {
+			if result = strings.TrimSpace(result); result != "" {
+				q.Results = append(q.Results, result)
+			}
+		}
+	}
+
+	switch params.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return q, errors.New("Invalid order: must be asc or desc")
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return q, errors.New("Invalid limit: must be a positive integer")
+		}
+		q.Limit = n
+	}
+
+	var err error
+	if q.From, err = parseQueryTime(params.Get("from"), false); err != nil {
+		return q, fmt.Errorf("Invalid from: %v", err)
+	}
+	if q.To, err = parseQueryTime(params.Get("to"), true); err != nil {
+		return q, fmt.Errorf("Invalid to: %v", err)
+	}
+	return q, nil
+}
+
+// parseListQuery reads limit and cursor for the listings with a fixed order, such as history and leagues
+func parseListQuery(r *http.Request) (db.ListQuery, error) {
+	q := db.ListQuery{Cursor: r.URL.Query().Get("cursor")}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return q, errors.New("Invalid limit: must be a positive integer")
+		}
+		q.Limit = n
+	}
+	return q, nil
+}
+
+// parseQueryTime parses a date or RFC 3339 time. endOfDay moves a bare date to the start of the next day
+func parseQueryTime(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, errors.New("must be a date (2006-01-02) or an RFC 3339 time")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
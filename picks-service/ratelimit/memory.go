@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryLimiter is a process-local token bucket limiter. Limits reset on restart and are not shared
+// between instances, so it's meant for local runs and single instance deployments
+type MemoryLimiter struct {
+	rate      Rate
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// how often idle buckets are dropped from memory
+const sweepInterval = time.Hour
+
+func NewMemoryLimiter(rate Rate) *MemoryLimiter {
+	return &MemoryLimiter{rate: rate, buckets: make(map[string]*bucket), lastSweep: time.Now(), now: time.Now}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string) (Decision, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.rate.Burst), updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = l.refill(b, now)
+	b.updated = now
+	if b.tokens < 1 {
+		return Decision{Allowed: false, RetryAfter: l.rate.retryAfter(b.tokens)}, nil
+	}
+
+	b.tokens--
+	return Decision{Allowed: true}, nil
+}
+
+func (l *MemoryLimiter) refill(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + now.Sub(b.updated).Seconds()*l.rate.perSecond()
+	if tokens > float64(l.rate.Burst) {
+		tokens = float64(l.rate.Burst)
+	}
+	return tokens
+}
+
+// sweep drops buckets that have refilled completely, since they're the same as a new bucket
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.rate.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
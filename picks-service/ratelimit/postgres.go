@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// PostgresLimiter keeps buckets in public.rate_limit_buckets so limits hold across restarts and replicas.
+// Each decision is a single upsert, so concurrent requests for the same key can't both take the last token
+type PostgresLimiter struct {
+	db          *sql.DB
+	rate        Rate
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+// buckets untouched for this long are deleted
+const bucketTTL = 24 * time.Hour
+
+func NewPostgresLimiter(db *sql.DB, rate Rate) *PostgresLimiter {
+	return &PostgresLimiter{db: db, rate: rate, lastCleanup: time.Now()}
+}
+
+func (l *PostgresLimiter) Allow(ctx context.Context, key string) (Decision, error) {
+	l.maybeCleanup()
+
+	//refilled is the bucket topped up for the time since its last update, capped at the burst size
+	sqlStatement := `INSERT INTO public.rate_limit_buckets AS b (bucket_key, tokens, allowed, updated_at)
+		VALUES ($1, $2::double precision - 1, true, clock_timestamp())
+		ON CONFLICT (bucket_key) DO UPDATE SET
+			tokens = CASE
+				WHEN LEAST($2, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * $3::double precision) >= 1
+				THEN LEAST($2, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * $3) - 1
+				ELSE LEAST($2, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * $3)
+			END,
+			allowed = LEAST($2, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * $3) >= 1,
+			updated_at = clock_timestamp()
+		RETURNING allowed, tokens;`
+
+	var allowed bool
+	var tokens float64
+	err := l.db.QueryRowContext(ctx, sqlStatement, key, float64(l.rate.Burst), l.rate.perSecond()).Scan(&allowed, &tokens)
+	if err != nil {
+		return Decision{}, fmt.Errorf("error updating rate limit bucket %s: %w", key, err)
+	}
+
+	if !allowed {
+		return Decision{Allowed: false, RetryAfter: l.rate.retryAfter(tokens)}, nil
+	}
+	return Decision{Allowed: true}, nil
+}
+
+// maybeCleanup deletes stale buckets in the background at most once an hour per instance
+func (l *PostgresLimiter) maybeCleanup() {
+	l.mu.Lock()
+	if time.Since(l.lastCleanup) < time.Hour {
+		l.mu.Unlock()
+		return
+	}
+	l.lastCleanup = time.Now()
+	l.mu.Unlock()
+
+	go func() {
+		sqlStatement := "DELETE FROM public.rate_limit_buckets WHERE updated_at < clock_timestamp() - $1 * INTERVAL '1 second';"
+		if _, err := l.db.Exec(sqlStatement, bucketTTL.Seconds()); err != nil {
+			log.Printf("Error cleaning up rate limit buckets: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,35 @@
+// ratelimit provides token bucket rate limiters that can be shared by every instance of a service
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Rate allows Burst requests at once, refilling one request every Every
+type Rate struct {
+	Burst int
+	Every time.Duration
+}
+
+func (r Rate) perSecond() float64 {
+	return float64(time.Second) / float64(r.Every)
+}
+
+// retryAfter is how long until a bucket holding tokens has a whole token again
+func (r Rate) retryAfter(tokens float64) time.Duration {
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - tokens) * float64(r.Every))
+}
+
+type Decision struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// Limiter decides whether a request identified by key may proceed
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Decision, error)
+}
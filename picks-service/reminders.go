@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"picks-service/db"
+	"picks-service/notify"
+)
+
+// how often the scheduler looks for events about to lock
+const reminderTick = time.Minute
+
+// parseReminderOffsets reads REMINDER_OFFSETS, e.g. "24h,1h", into offsets before lock, largest first.
+// "off" disables reminders
+func parseReminderOffsets(value string) ([]time.Duration, error) {
+	if strings.EqualFold(strings.TrimSpace(value), "off") {
+		return nil, nil
+	}
+
+	var offsets []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		offset, err := time.ParseDuration(part)
+		if err != nil || offset < time.Minute {
+			return nil, fmt.Errorf("invalid reminder offset %q: must be a duration of at least 1m", part)
+		}
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+	return offsets, nil
+}
+
+// newReminderNotifier picks the delivery channel from REMINDER_NOTIFIER: log (default), email or webhook
+func newReminderNotifier() (notify.Notifier, error) {
+	switch kind := getEnvWithFallback("REMINDER_NOTIFIER", "log"); kind {
+	case "log":
+		return notify.LogNotifier{}, nil
+	case "webhook":
+		url := os.Getenv("REMINDER_WEBHOOK_URL")
+		if url == "" {
+			return nil, fmt.Errorf("REMINDER_WEBHOOK_URL is required for the webhook notifier")
+		}
+		return notify.NewWebhookNotifier(url, os.Getenv("REMINDER_WEBHOOK_SECRET")), nil
+	case "email":
+		host, from := os.Getenv("SMTP_HOST"), os.Getenv("REMINDER_EMAIL_FROM")
+		if host == "" || from == "" {
+			return nil, fmt.Errorf("SMTP_HOST and REMINDER_EMAIL_FROM are required for the email notifier")
+		}
+		return &notify.EmailNotifier{
+			Host:     host,
+			Port:     getEnvWithFallback("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown REMINDER_NOTIFIER %q: use log, email or webhook", kind)
+	}
+}
+
+// runReminderScheduler sends pick reminders ahead of each event's lock under lockMode until the process exits.
+// offsets must be sorted largest first
+func runReminderScheduler(notifier notify.Notifier, offsets []time.Duration, lockMode db.LockMode) {
+	ticker := time.NewTicker(reminderTick)
+	defer ticker.Stop()
+
+	for {
+		sendDueReminders(notifier, offsets, lockMode, time.Now())
+		<-ticker.C
+	}
+}
+
+// sendDueReminders reminds users of every event whose lock is within an offset. Only the smallest offset
+// that is due gets sent, so a restart close to lock doesn't also send the stale earlier reminders
+func sendDueReminders(notifier notify.Notifier, offsets []time.Duration, lockMode db.LockMode, now time.Time) {
+	locks, err := db.GetUpcomingLocks(lockMode, now, offsets[0])
+	if err != nil {
+		log.Printf("Error finding events to remind users about: %v", err)
+		return
+	}
+
+	for _, lock := range locks {
+		var due time.Duration
+		for _, offset := range offsets {
+			if !lock.LocksAt.Add(-offset).After(now) {
+				due = offset
+			}
+		}
+		if due == 0 {
+			continue
+		}
+
+		recipients, err := db.ClaimReminderRecipients(lock.EventID, due, now)
+		if err != nil {
+			log.Printf("Error finding users to remind about event %s: %v", lock.EventID, err)
+			continue
+		}
+
+		sent := 0
+		for _, recipient := range recipients {
+			reminder := notify.Reminder{
+				UserID:    recipient.UserID,
+				Username:  recipient.Username,
+				Email:     recipient.Email,
+				EventID:   lock.EventID,
+				EventName: lock.EventName,
+				LocksAt:   lock.LocksAt,
+				Picked:    recipient.Picked,
+				Matchups:  recipient.Matchups,
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			err := notifier.Notify(ctx, reminder)
+			cancel()
+			if err != nil {
+				log.Printf("Error sending %s reminder for event %s to user %d: %v", due, lock.EventID, recipient.UserID, err)
+				if err := db.ReleaseReminder(recipient.UserID, lock.EventID, due); err != nil {
+					log.Printf("%v", err)
+				}
+				continue
+			}
+			sent++
+		}
+		if sent > 0 {
+			log.Printf("Sent %s reminders for event %s to %d users", due, lock.EventID, sent)
+		}
+	}
+}
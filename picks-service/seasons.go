@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"picks-service/db"
+)
+
+// seasonErrorStatus maps season errors to HTTP status codes
+func seasonErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrSeasonNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrInvalidSeasonArg):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrSeasonClosed), errors.Is(err, db.ErrSeasonOverlap):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// writeSeasonError responds with the mapped status, hiding unexpected errors behind message
+func writeSeasonError(w http.ResponseWriter, err error, message string) {
+	status := seasonErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, status)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func getSeasonsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	seasons, err := db.GetSeasons()
+	if err != nil {
+		writeSeasonError(w, err, "Error retrieving seasons")
+		return
+	}
+
+	writeJSON(w, seasons)
+}
+
+// creates a season: {"name": "2025", "startsOn": "2025-01-01", "endsOn": "2025-12-31"}. admin only
+func createSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+	if !requireAdmin(w, userId) {
+		return
+	}
+
+	var req struct {
+		Name     string `json:"name"`
+		StartsOn string `json:"startsOn"`
+		EndsOn   string `json:"endsOn"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	season, err := db.CreateSeason(req.Name, req.StartsOn, req.EndsOn)
+	if err != nil {
+		writeSeasonError(w, err, "Error creating season")
+		return
+	}
+
+	writeJSON(w, season)
+}
+
+// closes a season and archives its final standings: {"seasonId": 1}. admin only
+func closeSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+	if !requireAdmin(w, userId) {
+		return
+	}
+
+	var req struct {
+		SeasonID int `json:"seasonId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	season, err := db.CloseSeason(req.SeasonID)
+	if err != nil {
+		writeSeasonError(w, err, "Error closing season")
+		return
+	}
+
+	writeJSON(w, season)
+}
+
+// winners of every closed season, newest first
+func getSeasonChampionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	champions, err := db.GetSeasonChampions()
+	if err != nil {
+		writeSeasonError(w, err, "Error retrieving season champions")
+		return
+	}
+
+	writeJSON(w, champions)
+}
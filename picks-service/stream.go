@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"picks-service/db"
+	"picks-service/live"
+)
+
+// how often an idle stream sends a comment so proxies don't close it
+const streamKeepAlive = 25 * time.Second
+
+var liveUpdates = live.NewBroker()
+
+// handleEventUpdate turns a NOTIFY from any instance into messages for this instance's streams
+func handleEventUpdate(update db.EventUpdate) {
+	if liveUpdates.Subscribers(update.EventID) == 0 {
+		return
+	}
+
+	switch update.Type {
+	case db.UpdatePicks:
+		consensus, err := db.GetMatchupConsensus(update.MatchupID)
+		if err != nil {
+			log.Printf("Error loading consensus for live update: %v", err)
+			return
+		}
+		publishLiveUpdate(update.EventID, "consensus", consensus)
+	case db.UpdateResult:
+		result, err := db.GetMatchupResult(update.MatchupID)
+		if err != nil || result == nil {
+			log.Printf("Error loading result for live update of matchup %s: %v", update.MatchupID, err)
+			return
+		}
+		publishLiveUpdate(update.EventID, "result", result)
+	}
+}
+
+func publishLiveUpdate(eventId string, name string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Error encoding %s live update: %v", name, err)
+		return
+	}
+	liveUpdates.Publish(eventId, live.Message{Event: name, Data: data})
+}
+
+// streams an event's consensus changes and graded results as server-sent events. the first message is a
+// "snapshot" of the whole event's consensus, then "consensus" per matchup and "result" as fights are graded
+func streamEventHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	eventId := r.URL.Query().Get("eventId")
+	if eventId == "" {
+		http.Error(w, "Missing query parameter: eventId", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	//subscribe before the snapshot so nothing between the two is missed
+	messages, unsubscribe := liveUpdates.Subscribe(eventId)
+	defer unsubscribe()
+
+	snapshot, err := db.GetEventConsensus(eventId)
+	if err != nil {
+		log.Printf("Error retrieving consensus for event %s: %v", eventId, err)
+		http.Error(w, "Error retrieving consensus", http.StatusInternalServerError)
+		return
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		http.Error(w, "Error encoding consensus to JSON", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	writeServerSentEvent(w, live.Message{Event: "snapshot", Data: data})
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg := <-messages:
+			writeServerSentEvent(w, msg)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeServerSentEvent(w http.ResponseWriter, msg live.Message) {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Event, msg.Data)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"picks-service/db"
+)
+
+// survivorErrorStatus maps survivor errors to HTTP status codes
+func survivorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrSurvivorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrSurvivorNotEntered), errors.Is(err, db.ErrSurvivorEliminated):
+		return http.StatusForbidden
+	case errors.Is(err, db.ErrInvalidSurvivorArg), errors.Is(err, db.ErrInvalidPick), errors.Is(err, db.ErrInvalidListQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrSurvivorStarted), errors.Is(err, db.ErrSurvivorFighterUsed), errors.Is(err, db.ErrSurvivorAlreadyJoined):
+		return http.StatusConflict
+	case errors.Is(err, db.ErrPickLocked), errors.Is(err, db.ErrSurvivorPickLocked):
+		return http.StatusLocked
+	}
+	return http.StatusInternalServerError
+}
+
+// writeSurvivorError responds with the mapped status, hiding unexpected errors behind message
+func writeSurvivorError(w http.ResponseWriter, err error, message string) {
+	status := survivorErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, status)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func createSurvivorContestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	contest, err := db.CreateSurvivorContest(req.Name, userId)
+	if err != nil {
+		writeSurvivorError(w, err, "Error creating survivor contest")
+		return
+	}
+
+	writeJSON(w, contest)
+}
+
+// enters the user in a contest. entries close once the contest's first fight is graded
+func joinSurvivorContestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+
+	var req struct {
+		ContestID int `json:"contestId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.JoinSurvivorContest(req.ContestID, userId); err != nil {
+		writeSurvivorError(w, err, "Error joining survivor contest")
+		return
+	}
+
+	contest, err := db.GetSurvivorContest(req.ContestID)
+	if err != nil {
+		writeSurvivorError(w, err, "Error retrieving survivor contest")
+		return
+	}
+
+	writeJSON(w, contest)
+}
+
+// sets the user's survivor pick for an event:
+// {"contestId": 1, "eventId": "...", "matchupId": "...", "fighterId": "..."}
+func submitSurvivorPickHandler(store db.PickStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Invalid request method. Use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userId, _ := authenticatedUserId(r)
+
+		var req struct {
+			ContestID int    `json:"contestId"`
+			EventID   string `json:"eventId"`
+			MatchupID string `json:"matchupId"`
+			FighterID string `json:"fighterId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		pick, err := store.SubmitSurvivorPick(req.ContestID, userId, req.EventID, req.MatchupID, req.FighterID)
+		if err != nil {
+			writeSurvivorError(w, err, "Error saving survivor pick")
+			return
+		}
+
+		writeJSON(w, pick)
+	}
+}
+
+// the signed in user's picks in a contest, which also shows the fighters they have used up
+func getMySurvivorPicksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := authenticatedUserId(r)
+
+	contestId, err := strconv.Atoi(r.URL.Query().Get("contestId"))
+	if err != nil {
+		http.Error(w, "Invalid contestId: must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	query, err := parseListQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	picks, err := db.GetSurvivorPicks(contestId, userId, query)
+	if err != nil {
+		writeSurvivorError(w, err, "Error retrieving survivor picks")
+		return
+	}
+
+	writeJSON(w, picks)
+}
+
+func getSurvivorStandingsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	contestId, err := strconv.Atoi(r.URL.Query().Get("contestId"))
+	if err != nil {
+		http.Error(w, "Invalid contestId: must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	standings, err := db.GetSurvivorStandings(contestId)
+	if err != nil {
+		writeSurvivorError(w, err, "Error retrieving survivor standings")
+		return
+	}
+
+	writeJSON(w, standings)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"picks-service/db"
+)
+
+func TestSubmitSurvivorPickHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		userId string
+		body   string
+		want   int
+	}{
+		{name: "pick", userId: "1", body: `{"contestId": 1, "eventId": "e1", "matchupId": "m1", "fighterId": "f1"}`, want: http.StatusOK},
+		{name: "not entered", userId: "2", body: `{"contestId": 1, "eventId": "e1", "matchupId": "m1", "fighterId": "f1"}`, want: http.StatusForbidden},
+		{name: "locked fight", userId: "1", body: `{"contestId": 1, "eventId": "e1", "matchupId": "m2", "fighterId": "f3"}`, want: http.StatusLocked},
+		{name: "matchup not on the card", userId: "1", body: `{"contestId": 1, "eventId": "e1", "matchupId": "m9", "fighterId": "f1"}`, want: http.StatusBadRequest},
+		{name: "fighter not in the fight", userId: "1", body: `{"contestId": 1, "eventId": "e1", "matchupId": "m1", "fighterId": "f3"}`, want: http.StatusBadRequest},
+		{name: "missing fighter", userId: "1", body: `{"contestId": 1, "eventId": "e1", "matchupId": "m1"}`, want: http.StatusBadRequest},
+		{name: "malformed body", userId: "1", body: `{"contestId": `, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newHandlerTestStore(t)
+			store.EnterSurvivor(1, 1)
+
+			w := serve(t, submitSurvivorPickHandler(store), http.MethodPost, "/api/v1/submitSurvivorPick", tt.body, tt.userId)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var pick db.SurvivorPick
+			if err := json.NewDecoder(w.Body).Decode(&pick); err != nil {
+				t.Fatalf("decoding pick: %v", err)
+			}
+			if pick.Result != db.PickResultPending || pick.FighterID != "f1" {
+				t.Errorf("pick = %+v, want a pending pick on f1", pick)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"server/db"
+	"shared/auth"
 
 	"time"
 
@@ -25,7 +26,6 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -88,10 +88,11 @@ func main() {
 
 	_ = godotenv.Load()
 	fmt.Printf("Environment variables present: JWT_SECRET=%v\n", os.Getenv("JWT_SECRET") != "")
-	jwtKey = []byte(getEnvWithFallback("JWT_SECRET", "your-default-secret-key"))
-	if len(jwtKey) == 0 {
-		log.Fatal("JWT_SECRET not set in environment")
+	key, err := auth.SigningKeyFromEnv()
+	if err != nil {
+		log.Fatal(err)
 	}
+	jwtKey = key
 
 	db.StartUsersDbConnection()
 
@@ -151,22 +152,8 @@ func generateJWT(email string) (string, error) {
 		fmt.Println("Error in retrieval of username")
 	}
 
-	//define custom claims struct to include UserId
-	claims := struct {
-		Username string `json:"username"`
-		UserId   string `json:"userId"`
-		jwt.RegisteredClaims
-	}{
-		Username: username,
-		UserId:   userId,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
-	}
-
-	//create the jwt token with claims defined above
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	//create the jwt token with the shared claims (also parsed by picks-service)
+	return auth.NewToken(username, userId, expirationTime, jwtKey)
 }
 
 // hashes the password
@@ -346,49 +333,21 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 // authentication func using JWT
 func authenticate(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var tokenString string
-
-		// First try to get token from cookie
-		cookie, err := r.Cookie("token")
-		if err == nil {
-			tokenString = cookie.Value
-		} else {
-			// If no cookie, check Authorization header
-			authHeader := r.Header.Get("Authorization")
-			if strings.HasPrefix(authHeader, "Bearer ") {
-				tokenString = strings.TrimPrefix(authHeader, "Bearer ")
-			}
-		}
-
-		if tokenString == "" {
+		// Try the cookie first, then the Authorization header
+		tokenString, err := auth.TokenFromRequest(r)
+		if err != nil {
 			log.Printf("No token found in cookie or Authorization header")
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
 		// Parse and validate the token
-		claims := &struct {
-			Username string `json:"username"`
-			UserId   string `json:"userId"`
-			jwt.RegisteredClaims
-		}{}
-
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-
-		if err != nil {
+		if _, err := auth.ParseToken(tokenString, jwtKey); err != nil {
 			log.Printf("Token parsing failed: %v", err)
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		if !token.Valid {
-			log.Printf("Token is invalid")
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
 		next(w, r)
 	}
 }
@@ -456,30 +415,12 @@ func authStatusHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse and validate the token
-	token, err := jwt.ParseWithClaims(cookie.Value, &struct {
-		Username string `json:"username"`
-		UserId   string `json:"userId"`
-		jwt.RegisteredClaims
-	}{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
-
-	if err != nil || !token.Valid {
+	claims, err := auth.ParseToken(cookie.Value, jwtKey)
+	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Extract claims
-	claims, ok := token.Claims.(*struct {
-		Username string `json:"username"`
-		UserId   string `json:"userId"`
-		jwt.RegisteredClaims
-	})
-	if !ok {
-		http.Error(w, "Invalid token claims", http.StatusUnauthorized)
-		return
-	}
-
 	// Get user email and profile picture from database using userId
 	email, err := db.SelectEmail(claims.UserId)
 	if err != nil {
@@ -685,27 +626,14 @@ func isValidImageType(filename string) bool {
 }
 
 // Add this function to get claims from token
-func getUserClaimsFromToken(r *http.Request) *struct {
-	Username string `json:"username"`
-	UserId   string `json:"userId"`
-	jwt.RegisteredClaims
-} {
+func getUserClaimsFromToken(r *http.Request) *auth.Claims {
 	cookie, err := r.Cookie("token")
 	if err != nil {
 		return nil
 	}
 
-	claims := &struct {
-		Username string `json:"username"`
-		UserId   string `json:"userId"`
-		jwt.RegisteredClaims
-	}{}
-
-	token, err := jwt.ParseWithClaims(cookie.Value, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
-
-	if err != nil || !token.Valid {
+	claims, err := auth.ParseToken(cookie.Value, jwtKey)
+	if err != nil {
 		return nil
 	}
 
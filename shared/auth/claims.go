@@ -0,0 +1,97 @@
+// auth holds the JWT claims shared by the server (which issues tokens) and the services that validate them
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// name of the cookie the server sets on login
+const TokenCookieName = "token"
+
+var ErrNoToken = errors.New("no token found in cookie or Authorization header")
+
+var ErrNoSigningKey = errors.New("JWT_SECRET must be set")
+
+// SigningKeyFromEnv reads the HS256 key from JWT_SECRET. There is no fallback: tokens signed with a
+// guessable key could impersonate anyone, so callers should refuse to start without one
+func SigningKeyFromEnv() ([]byte, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, ErrNoSigningKey
+	}
+	return []byte(secret), nil
+}
+
+// Claims is the payload of every token issued on login
+type Claims struct {
+	Username string `json:"username"`
+	UserId   string `json:"userId"`
+	jwt.RegisteredClaims
+}
+
+// NewToken signs claims for the given user with HS256
+func NewToken(username string, userId string, expiresAt time.Time, key []byte) (string, error) {
+	claims := Claims{
+		Username: username,
+		UserId:   userId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(key)
+}
+
+// ParseToken validates an HS256 token and returns its claims
+func ParseToken(tokenString string, key []byte) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		//reject anything that isn't signed the way the server signs it (e.g. alg "none" or RS256 key confusion)
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, errors.New("token is invalid")
+	}
+
+	return claims, nil
+}
+
+// TokenFromRequest reads the token from the "token" cookie, falling back to an Authorization: Bearer header
+func TokenFromRequest(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(TokenCookieName)
+	if err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		return strings.TrimPrefix(authHeader, "Bearer "), nil
+	}
+
+	return "", ErrNoToken
+}
+
+// ClaimsFromRequest combines TokenFromRequest and ParseToken
+func ClaimsFromRequest(r *http.Request, key []byte) (*Claims, error) {
+	tokenString, err := TokenFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseToken(tokenString, key)
+}
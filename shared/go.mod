@@ -0,0 +1,5 @@
+module shared
+
+go 1.23.1
+
+require github.com/golang-jwt/jwt/v4 v4.5.1